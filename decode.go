@@ -0,0 +1,437 @@
+package customcsv
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// structField is a struct field mapped to a column.
+type structField struct {
+	name      string
+	index     []int
+	column    int
+	omitEmpty bool
+	layout    string
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	structFieldsCache sync.Map
+)
+
+// structFields returns the fields of the struct type that are mapped to columns.
+//
+// The mapping is specified by the "csv" struct tag.
+//
+//	Field int `csv:"name"`          // Mapped to the column with header "name".
+//	Field int `csv:"name,omitempty"` // Empty values and missing columns are allowed.
+//	Field int `csv:",index=2"`       // Mapped to the third column.
+//	Field int `csv:"-"`              // Ignored.
+//
+// If the name is omitted, the field name is used.
+// The fields of an embedded struct are flattened, and the tag name of the embedded struct is used as a prefix.
+// The layout of time.Time is specified by the "layout" struct tag. (default: time.RFC3339)
+func structFields(t reflect.Type) ([]structField, error) {
+
+	if cached, ok := structFieldsCache.Load(t); ok {
+		return cached.([]structField), nil
+	}
+
+	fields, err := collectStructFields(t, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	structFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+func collectStructFields(t reflect.Type, prefix string, parentIndex []int) ([]structField, error) {
+
+	fields := []structField{}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+
+		index := make([]int, len(parentIndex)+1)
+		copy(index, parentIndex)
+		index[len(parentIndex)] = i
+
+		name, options := parseTag(tag)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && !isValueType(sf.Type) {
+			// Flatten the embedded struct.
+			embedded, err := collectStructFields(sf.Type, prefix+name, index)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+
+		if !isValueType(sf.Type) {
+			return nil, fmt.Errorf("unsupported type %v of field %s", sf.Type, sf.Name)
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		field := structField{
+			name:   prefix + name,
+			index:  index,
+			column: -1,
+			layout: sf.Tag.Get("layout"),
+		}
+
+		for _, option := range options {
+			switch {
+			case option == "omitempty":
+				field.omitEmpty = true
+			case strings.HasPrefix(option, "index="):
+				column, err := strconv.Atoi(strings.TrimPrefix(option, "index="))
+				if err != nil || column < 0 {
+					return nil, fmt.Errorf("invalid index option %q of field %s", option, sf.Name)
+				}
+				field.column = column
+			default:
+				return nil, fmt.Errorf("unknown option %q of field %s", option, sf.Name)
+			}
+		}
+
+		if field.layout == "" {
+			field.layout = time.RFC3339
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func parseTag(tag string) (string, []string) {
+
+	if tag == "" {
+		return "", nil
+	}
+
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// isValueType reports whether the type can be converted from/to a single field.
+func isValueType(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return true
+	}
+
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// Decoder reads records from a Reader and stores them in structs.
+type Decoder struct {
+	r       *Reader
+	t       reflect.Type
+	fields  []structField
+	columns []int
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+// If r.HasHeader is true, the fields are mapped by header name.
+// Otherwise, they are mapped by the index option or in the order of the struct fields.
+func NewDecoder(r *Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// Unmarshal parses the CSV with a header and stores the records in the slice pointed to by v.
+func Unmarshal(data []byte, v interface{}) error {
+
+	r := NewReader(bytes.NewReader(data))
+	r.HasHeader = true
+
+	return NewDecoder(r).DecodeAll(v)
+}
+
+// Decode reads one record and stores it in the struct pointed to by v.
+// At the end of the input, it returns io.EOF.
+func (d *Decoder) Decode(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("decode target must be a non-nil pointer to a struct")
+	}
+
+	if err := d.prepare(rv.Elem().Type()); err != nil {
+		return err
+	}
+
+	record, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+
+	return d.decodeRecord(record, rv.Elem())
+}
+
+// DecodeAll reads all the remaining records and stores them in the slice pointed to by v.
+// The element type of the slice must be a struct or a pointer to a struct.
+func (d *Decoder) DecodeAll(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("decode target must be a non-nil pointer to a slice")
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errors.New("decode target must be a slice of structs")
+	}
+
+	if err := d.prepare(structType); err != nil {
+		if err == io.EOF {
+			// Empty input.
+			return nil
+		}
+		return err
+	}
+
+	for {
+		record, err := d.r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		elem := reflect.New(structType).Elem()
+		if err := d.decodeRecord(record, elem); err != nil {
+			return err
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			slice.Set(reflect.Append(slice, elem.Addr()))
+		} else {
+			slice.Set(reflect.Append(slice, elem))
+		}
+	}
+}
+
+func (d *Decoder) prepare(t reflect.Type) error {
+
+	if d.t == t {
+		return nil
+	}
+
+	fields, err := structFields(t)
+	if err != nil {
+		return err
+	}
+
+	if !d.r.HasHeader {
+		// The same columns as Encoder without the header.
+		columns, _, err := structColumns(fields)
+		if err != nil {
+			return err
+		}
+
+		d.t = t
+		d.fields = fields
+		d.columns = columns
+		return nil
+	}
+
+	columns := make([]int, len(fields))
+	for i, field := range fields {
+
+		if field.column >= 0 {
+			columns[i] = field.column
+			continue
+		}
+
+		column, err := d.r.HeaderIndex(field.name)
+		if err != nil {
+			return err
+		}
+		if column == -1 && !field.omitEmpty {
+			return fmt.Errorf("header name %q not found", field.name)
+		}
+		columns[i] = column
+	}
+
+	d.t = t
+	d.fields = fields
+	d.columns = columns
+	return nil
+}
+
+// structColumns returns the column of each field and the number of columns.
+// Fields with the index option are placed in the specified column,
+// and the other fields fill the remaining columns in order.
+func structColumns(fields []structField) ([]int, int, error) {
+
+	numColumn := len(fields)
+	for _, field := range fields {
+		if field.column >= numColumn {
+			numColumn = field.column + 1
+		}
+	}
+
+	used := make([]bool, numColumn)
+	columns := make([]int, len(fields))
+	for i, field := range fields {
+		columns[i] = field.column
+		if field.column >= 0 {
+			if used[field.column] {
+				return nil, 0, fmt.Errorf("duplicate index option %d", field.column)
+			}
+			used[field.column] = true
+		}
+	}
+
+	next := 0
+	for i, field := range fields {
+		if field.column >= 0 {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		columns[i] = next
+		used[next] = true
+	}
+
+	return columns, numColumn, nil
+}
+
+func (d *Decoder) decodeRecord(record []string, v reflect.Value) error {
+
+	for i, field := range d.fields {
+		column := d.columns[i]
+		if column < 0 || column >= len(record) {
+			continue
+		}
+
+		value := record[column]
+		if value == "" && field.omitEmpty {
+			continue
+		}
+
+		if err := setField(v.FieldByIndex(field.index), value, field.layout); err != nil {
+			if ne, ok := err.(*strconv.NumError); ok {
+				err = ne.Err
+			}
+			message := fmt.Sprintf("cannot convert %q to %v: %v", value, v.FieldByIndex(field.index).Type(), err)
+			return d.r.fieldError(column, message, err)
+		}
+	}
+
+	return nil
+}
+
+func setField(v reflect.Value, s string, layout string) error {
+
+	if v.Kind() == reflect.Ptr {
+		if s == "" {
+			// An empty value is nil.
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package customcsv
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodeItem struct {
+	ID      int       `csv:"id"`
+	Name    string    `csv:"name"`
+	Price   float64   `csv:"price"`
+	Active  bool      `csv:"active"`
+	Created time.Time `csv:"created" layout:"2006-01-02"`
+	Note    *string   `csv:"note"`
+	Count   *int      `csv:"count,omitempty"`
+	Ignored string    `csv:"-"`
+}
+
+type upperText string
+
+func (u *upperText) UnmarshalText(text []byte) error {
+	*u = upperText(strings.ToUpper(string(text)))
+	return nil
+}
+
+func TestUnmarshal(t *testing.T) {
+
+	s := `id,name,price,active,created,note
+1,"a,b",1.5,true,2021-05-01,x
+2,c,-2,false,2021-12-31,
+`
+
+	var items []decodeItem
+	if err := Unmarshal([]byte(s), &items); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	note := "x"
+	expected := []decodeItem{
+		{ID: 1, Name: "a,b", Price: 1.5, Active: true, Created: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), Note: &note},
+		{ID: 2, Name: "c", Price: -2, Active: false, Created: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), Note: nil},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Fatal("failed test\n", items)
+	}
+}
+
+func TestUnmarshal_PointerElements(t *testing.T) {
+
+	s := `name,ID
+a,1
+`
+
+	var items []*struct {
+		ID   int
+		Name string `csv:"name"`
+	}
+	if err := Unmarshal([]byte(s), &items); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if len(items) != 1 || items[0].ID != 1 || items[0].Name != "a" {
+		t.Fatal("failed test\n", items)
+	}
+}
+
+func TestUnmarshal_Empty(t *testing.T) {
+
+	var items []decodeItem
+	if err := Unmarshal([]byte(""), &items); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if len(items) != 0 {
+		t.Fatal("failed test\n", items)
+	}
+}
+
+func TestUnmarshal_ConvertError(t *testing.T) {
+
+	s := `id,name,price,active,created,note
+1,a,1.5,true,2021-05-01,
+2,b,x,true,2021-05-01,
+`
+
+	var items []decodeItem
+	err := Unmarshal([]byte(s), &items)
+	if err == nil || err.Error() != `parse error on record 3, column 3: cannot convert "x" to float64: invalid syntax` {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestUnmarshal_HeaderNotFound(t *testing.T) {
+
+	s := `id,name
+1,a
+`
+
+	var items []decodeItem
+	err := Unmarshal([]byte(s), &items)
+	if err == nil || err.Error() != `header name "price" not found` {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestUnmarshal_InvalidTarget(t *testing.T) {
+
+	var item decodeItem
+	if err := Unmarshal([]byte("id\n1\n"), &item); err == nil {
+		t.Fatal("failed test\n")
+	}
+
+	var values []string
+	if err := Unmarshal([]byte("id\n1\n"), &values); err == nil {
+		t.Fatal("failed test\n")
+	}
+}
+
+func TestUnmarshal_UnsupportedType(t *testing.T) {
+
+	var items []struct {
+		Values []string
+	}
+	err := Unmarshal([]byte("Values\n1\n"), &items)
+	if err == nil || err.Error() != "unsupported type []string of field Values" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestDecoder_Decode(t *testing.T) {
+
+	s := `code,value
+a,1
+b,
+`
+
+	type item struct {
+		Code  upperText `csv:"code"`
+		Value int       `csv:"value,omitempty"`
+	}
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	d := NewDecoder(r)
+
+	// record:2
+	{
+		var v item
+		if err := d.Decode(&v); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if v.Code != "A" || v.Value != 1 {
+			t.Fatal("failed test\n", v)
+		}
+	}
+
+	// record:3
+	{
+		// The empty value is not converted because of omitempty.
+		var v item
+		if err := d.Decode(&v); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if v.Code != "B" || v.Value != 0 {
+			t.Fatal("failed test\n", v)
+		}
+	}
+
+	var v item
+	if err := d.Decode(&v); err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestDecoder_NoHeader(t *testing.T) {
+
+	s := `1,a,x
+2,b,y
+`
+
+	type item struct {
+		Name string `csv:",index=1"`
+		ID   int    `csv:",index=0"`
+	}
+
+	r := NewReader(strings.NewReader(s))
+	d := NewDecoder(r)
+
+	var items []item
+	if err := d.DecodeAll(&items); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := []item{
+		{Name: "a", ID: 1},
+		{Name: "b", ID: 2},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Fatal("failed test\n", items)
+	}
+}
+
+func TestDecoder_NoHeader_FieldOrder(t *testing.T) {
+
+	s := `1,a
+`
+
+	type item struct {
+		ID   int
+		Name string
+	}
+
+	r := NewReader(strings.NewReader(s))
+	d := NewDecoder(r)
+
+	var v item
+	if err := d.Decode(&v); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if v.ID != 1 || v.Name != "a" {
+		t.Fatal("failed test\n", v)
+	}
+}
+
+func TestDecoder_Embedded(t *testing.T) {
+
+	s := `id,addr_city,addr_zip
+1,Tokyo,100
+`
+
+	type Address struct {
+		City string `csv:"city"`
+		Zip  string `csv:"zip"`
+	}
+	type item struct {
+		ID      int `csv:"id"`
+		Address `csv:"addr_"`
+	}
+
+	var items []item
+	if err := Unmarshal([]byte(s), &items); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := []item{
+		{ID: 1, Address: Address{City: "Tokyo", Zip: "100"}},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Fatal("failed test\n", items)
+	}
+}
+
+func TestDecoder_ParseErrorPosition(t *testing.T) {
+
+	s := "id,name\n" +
+		"\"1\n\",a\n"
+
+	type item struct {
+		ID   int    `csv:"id"`
+		Name string `csv:"name"`
+	}
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	d := NewDecoder(r)
+
+	var v item
+	err := d.Decode(&v)
+
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatal("failed test\n", err)
+	}
+
+	if pe.Record != 2 || pe.Column != 1 || pe.StartLine != 2 || pe.Line != 2 || pe.ByteOffset != 8 || pe.Err == nil {
+		t.Fatal("failed test\n", pe)
+	}
+}
@@ -0,0 +1,290 @@
+package customcsv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dialect is the format shared by Reader and Writer.
+// The presets such as DialectRFC4180 return a new Dialect for each call,
+// so a returned Dialect can be modified without affecting the others.
+type Dialect struct {
+	// Delimiter is the field delimiter.
+	Delimiter rune
+
+	// DelimiterString is the field delimiter of multiple characters, such as "||".
+	// If specified, it is used instead of Delimiter.
+	DelimiterString string
+
+	// Quote is the field quote character.
+	Quote rune
+
+	// OpenQuote and CloseQuote are the quote characters at the beginning and the end of a field.
+	// If 0, Quote is used.
+	OpenQuote  rune
+	CloseQuote rune
+
+	// QuoteNone disables quoting.
+	QuoteNone bool
+
+	// QuotePolicy is the policy to quote the fields in Writer.
+	QuotePolicy QuotePolicy
+
+	// Escape is the escape character. If 0, escapes are not used.
+	Escape rune
+
+	// EscapeSequences indicates that "\n", "\t", "\r" and "\0" following the Escape character are used.
+	EscapeSequences bool
+
+	// RecordSeparator is the record separator.
+	// If it is a newline ("\r\n", "\n" or "\r"), Reader accepts any newline as the record separator.
+	// If empty, Writer uses CRLF ("\r\n").
+	RecordSeparator string
+
+	// Comment is the comment character. If 0, comments are not used.
+	Comment rune
+
+	// HasHeader indicates that the first record is a header.
+	HasHeader bool
+
+	// BOM indicates that the input begins with the UTF-8 BOM.
+	// Reader skips the BOM regardless of it, and Writer writes the BOM.
+	BOM bool
+}
+
+// DialectRFC4180 returns the format defined in RFC 4180.
+func DialectRFC4180() Dialect {
+	return Dialect{
+		Delimiter:       ',',
+		Quote:           '"',
+		RecordSeparator: "\r\n",
+	}
+}
+
+// DialectExcel returns the CSV format of Microsoft Excel.
+// It is RFC 4180 with the UTF-8 BOM, by which Excel recognizes the input as UTF-8.
+func DialectExcel() Dialect {
+	return Dialect{
+		Delimiter:       ',',
+		Quote:           '"',
+		RecordSeparator: "\r\n",
+		BOM:             true,
+	}
+}
+
+// DialectExcelTSV returns the tab-delimited format of Microsoft Excel.
+func DialectExcelTSV() Dialect {
+	return Dialect{
+		Delimiter:       '\t',
+		Quote:           '"',
+		RecordSeparator: "\r\n",
+	}
+}
+
+// DialectUnix returns the format with LF as the record separator and all fields quoted.
+func DialectUnix() Dialect {
+	return Dialect{
+		Delimiter:       ',',
+		Quote:           '"',
+		QuotePolicy:     QuoteAll,
+		RecordSeparator: "\n",
+	}
+}
+
+// DialectPostgreSQL returns the CSV format of the COPY command of PostgreSQL.
+func DialectPostgreSQL() Dialect {
+	return Dialect{
+		Delimiter:       ',',
+		Quote:           '"',
+		RecordSeparator: "\n",
+	}
+}
+
+// DialectMySQL returns the default format of SELECT ... INTO OUTFILE and LOAD DATA of MySQL.
+func DialectMySQL() Dialect {
+	return Dialect{
+		Delimiter:       '\t',
+		Quote:           '"',
+		QuoteNone:       true,
+		Escape:          '\\',
+		EscapeSequences: true,
+		RecordSeparator: "\n",
+	}
+}
+
+// ErrInvalidDialect is the error that the format characters conflict with each other.
+var ErrInvalidDialect = errors.New("invalid dialect")
+
+// NewReaderDialect returns a new Reader that reads from r in the dialect.
+func NewReaderDialect(r io.Reader, d Dialect) *Reader {
+
+	reader := NewReader(r)
+	reader.Delimiter = d.Delimiter
+	reader.DelimiterString = d.DelimiterString
+	reader.Quote = d.Quote
+	reader.OpenQuote = d.OpenQuote
+	reader.CloseQuote = d.CloseQuote
+	reader.QuoteNone = d.QuoteNone
+	reader.Escape = d.Escape
+	reader.EscapeSequences = d.EscapeSequences
+	reader.Comment = d.Comment
+	reader.HasHeader = d.HasHeader
+
+	if !isNewline(d.RecordSeparator) {
+		reader.SpecialRecordSeparator = d.RecordSeparator
+	}
+
+	return reader
+}
+
+// NewWriterDialect returns a new Writer that writes to w in the dialect.
+func NewWriterDialect(w io.Writer, d Dialect) *Writer {
+
+	writer := NewWriter(w)
+	writer.Delimiter = d.Delimiter
+	writer.DelimiterString = d.DelimiterString
+	writer.Quote = d.Quote
+	writer.OpenQuote = d.OpenQuote
+	writer.CloseQuote = d.CloseQuote
+	writer.QuoteNone = d.QuoteNone
+	writer.QuotePolicy = d.QuotePolicy
+	writer.Escape = d.Escape
+	writer.EscapeSequences = d.EscapeSequences
+	writer.BOM = d.BOM
+
+	if d.RecordSeparator != "" {
+		writer.RecordSeparator = d.RecordSeparator
+	}
+	if d.Comment != 0 {
+		writer.Comment = d.Comment
+	}
+
+	return writer
+}
+
+// isNewline reports whether the record separator is a newline, which is the default of Reader.
+func isNewline(s string) bool {
+	return s == "" || s == "\r\n" || s == "\n" || s == "\r"
+}
+
+// Validate checks that the format characters do not conflict with each other.
+// It returns an error wrapping ErrInvalidDialect, which lists all the conflicts.
+func (d Dialect) Validate() error {
+	return invalidDialectError(d.conflicts())
+}
+
+// invalidDialectError returns an error wrapping ErrInvalidDialect that lists the conflicts, or nil if there are none.
+func invalidDialectError(conflicts []string) error {
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidDialect, strings.Join(conflicts, "; "))
+}
+
+// conflicts returns the descriptions of the conflicts between the format characters.
+func (d Dialect) conflicts() []string {
+
+	conflicts := []string{}
+
+	// formats are the format characters that must be distinguishable from each other.
+	type format struct {
+		name string
+		text string
+	}
+	formats := []format{}
+
+	addRune := func(name string, r rune) {
+		if !isValidFormatRune(r) {
+			conflicts = append(conflicts, fmt.Sprintf("%s %q is not a valid character", name, r))
+			return
+		}
+		formats = append(formats, format{name, string(r)})
+	}
+
+	if d.DelimiterString != "" {
+		if !utf8.ValidString(d.DelimiterString) {
+			conflicts = append(conflicts, fmt.Sprintf("delimiter %q is not valid UTF-8", d.DelimiterString))
+		} else {
+			formats = append(formats, format{"delimiter", d.DelimiterString})
+		}
+	} else {
+		addRune("delimiter", d.Delimiter)
+	}
+
+	if !d.QuoteNone {
+		openQuote, closeQuote := d.quotes()
+		if openQuote == closeQuote {
+			addRune("quote", openQuote)
+		} else {
+			addRune("open quote", openQuote)
+			addRune("close quote", closeQuote)
+		}
+	}
+
+	if d.Escape != 0 {
+		addRune("escape", d.Escape)
+	}
+	if d.Comment != 0 {
+		addRune("comment", d.Comment)
+	}
+
+	for i := 0; i < len(formats); i++ {
+		for j := i + 1; j < len(formats); j++ {
+			a, b := formats[i], formats[j]
+			if strings.Contains(a.text, b.text) || strings.Contains(b.text, a.text) {
+				conflicts = append(conflicts, fmt.Sprintf("%s %q conflicts with %s %q", a.name, a.text, b.name, b.text))
+			}
+		}
+	}
+
+	if !utf8.ValidString(d.RecordSeparator) {
+		conflicts = append(conflicts, fmt.Sprintf("record separator %q is not valid UTF-8", d.RecordSeparator))
+		return conflicts
+	}
+
+	for _, f := range formats {
+		var conflict bool
+		switch {
+		case isNewline(d.RecordSeparator):
+			// Any newline is a record separator in Reader.
+			conflict = strings.ContainsAny(f.text, "\r\n")
+		case f.name == "delimiter":
+			// If both match, the longer one is used. So only the same one is a conflict.
+			conflict = f.text == d.RecordSeparator
+		case f.name == "comment":
+			// The comment character is only at the beginning of a record.
+			conflict = f.text == d.RecordSeparator
+		default:
+			conflict = strings.Contains(d.RecordSeparator, f.text)
+		}
+
+		if conflict {
+			conflicts = append(conflicts, fmt.Sprintf("%s %q conflicts with record separator %q", f.name, f.text, d.RecordSeparator))
+		}
+	}
+
+	return conflicts
+}
+
+// quotes returns the quote characters at the beginning and the end of a field.
+func (d Dialect) quotes() (rune, rune) {
+
+	openQuote, closeQuote := d.Quote, d.Quote
+	if d.OpenQuote != 0 {
+		openQuote = d.OpenQuote
+	}
+	if d.CloseQuote != 0 {
+		closeQuote = d.CloseQuote
+	}
+	return openQuote, closeQuote
+}
+
+// isValidFormatRune reports whether the rune can be used as a format character.
+func isValidFormatRune(r rune) bool {
+	return r != 0 && r != utf8.RuneError && utf8.ValidRune(r)
+}
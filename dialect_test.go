@@ -0,0 +1,221 @@
+package customcsv
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDialect_Presets(t *testing.T) {
+
+	records := [][]string{
+		{"a", "b,c", "d\"e"},
+		{"", "f\ng", "h\\i"},
+		{"1", "\tj", "k\r\nl"},
+	}
+
+	dialects := map[string]Dialect{
+		"RFC4180":    DialectRFC4180(),
+		"Excel":      DialectExcel(),
+		"ExcelTSV":   DialectExcelTSV(),
+		"Unix":       DialectUnix(),
+		"PostgreSQL": DialectPostgreSQL(),
+		"MySQL":      DialectMySQL(),
+	}
+
+	for name, dialect := range dialects {
+		if err := dialect.Validate(); err != nil {
+			t.Fatal("failed test\n", name, err)
+		}
+
+		var b bytes.Buffer
+		w := NewWriterDialect(&b, dialect)
+		if err := w.WriteAll(records); err != nil {
+			t.Fatal("failed test\n", name, err)
+		}
+
+		r := NewReaderDialect(strings.NewReader(b.String()), dialect)
+		result, err := r.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", name, err)
+		}
+
+		if !reflect.DeepEqual(result, records) {
+			t.Fatal("failed test\n", name, result)
+		}
+	}
+}
+
+func TestDialect_Output(t *testing.T) {
+
+	tests := []struct {
+		dialect Dialect
+		expect  string
+	}{
+		{dialect: DialectRFC4180(), expect: "a,\"b,c\"\r\n"},
+		{dialect: DialectExcel(), expect: "\uFEFFa,\"b,c\"\r\n"},
+		{dialect: DialectExcelTSV(), expect: "a\tb,c\r\n"},
+		{dialect: DialectUnix(), expect: "\"a\",\"b,c\"\n"},
+		{dialect: DialectPostgreSQL(), expect: "a,\"b,c\"\n"},
+		{dialect: DialectMySQL(), expect: "a\tb,c\n"},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		w := NewWriterDialect(&b, test.dialect)
+		if err := w.WriteAll([][]string{{"a", "b,c"}}); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if b.String() != test.expect {
+			t.Fatal("failed test\n", b.String())
+		}
+	}
+}
+
+func TestDialect_Presets_Modified(t *testing.T) {
+
+	d := DialectExcel()
+	d.Delimiter = ';'
+
+	// The preset is not changed.
+	if DialectExcel().Delimiter != ',' {
+		t.Fatal("failed test\n", DialectExcel())
+	}
+}
+
+func TestNewWriterDialect_BOM(t *testing.T) {
+
+	d := DialectRFC4180()
+	d.BOM = true
+
+	var b bytes.Buffer
+	w := NewWriterDialect(&b, d)
+	if err := w.WriteAll([][]string{{"a", "b"}}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if b.String() != "\uFEFFa,b\r\n" {
+		t.Fatal("failed test\n", b.String())
+	}
+}
+
+func TestNewReaderDialect_Newline(t *testing.T) {
+
+	// Any newline is accepted, even if the record separator of the dialect is CRLF.
+	r := NewReaderDialect(strings.NewReader("a,b\nc,d\re,f\r\n"), DialectRFC4180())
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"a", "b"}, {"c", "d"}, {"e", "f"}}) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReaderDialect_SpecialRecordSeparator(t *testing.T) {
+
+	d := Dialect{
+		DelimiterString: "||",
+		OpenQuote:       '「',
+		CloseQuote:      '」',
+		RecordSeparator: "\x1e",
+		Comment:         '#',
+	}
+
+	r := NewReaderDialect(strings.NewReader("#x\x1ea||「b\x1e」\x1e"), d)
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"a", "b\x1e"}}) {
+		t.Fatal("failed test\n", records)
+	}
+
+	var b bytes.Buffer
+	w := NewWriterDialect(&b, d)
+	if err := w.WriteAll(records); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if b.String() != "a||「b\x1e」\x1e" {
+		t.Fatal("failed test\n", b.String())
+	}
+}
+
+func TestDialect_Validate(t *testing.T) {
+
+	tests := []struct {
+		dialect Dialect
+		message string
+	}{
+		{
+			dialect: Dialect{Delimiter: ',', Quote: ','},
+			message: `invalid dialect: delimiter "," conflicts with quote ","`,
+		},
+		{
+			dialect: Dialect{Delimiter: utf8.RuneError, Quote: '"'},
+			message: `invalid dialect: delimiter '�' is not a valid character`,
+		},
+		{
+			dialect: Dialect{Delimiter: ',', Quote: '"', RecordSeparator: "\"\n"},
+			message: `invalid dialect: quote "\"" conflicts with record separator "\"\n"`,
+		},
+		{
+			dialect: Dialect{Delimiter: '\n', Quote: '"'},
+			message: `invalid dialect: delimiter "\n" conflicts with record separator ""`,
+		},
+		{
+			dialect: Dialect{Delimiter: '|', Quote: '"', RecordSeparator: "|"},
+			message: `invalid dialect: delimiter "|" conflicts with record separator "|"`,
+		},
+		{
+			dialect: Dialect{DelimiterString: "~|~", Quote: '|', Escape: '|'},
+			message: `invalid dialect: delimiter "~|~" conflicts with quote "|"; ` +
+				`delimiter "~|~" conflicts with escape "|"; quote "|" conflicts with escape "|"`,
+		},
+		{
+			dialect: Dialect{Delimiter: ',', OpenQuote: '[', CloseQuote: ','},
+			message: `invalid dialect: delimiter "," conflicts with close quote ","`,
+		},
+		{
+			dialect: Dialect{Delimiter: ',', Quote: '"', Comment: ','},
+			message: `invalid dialect: delimiter "," conflicts with comment ","`,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.dialect.Validate()
+		if !errors.Is(err, ErrInvalidDialect) {
+			t.Fatal("failed test\n", err)
+		}
+
+		if err.Error() != test.message {
+			t.Fatal("failed test\n", err)
+		}
+	}
+}
+
+func TestDialect_Validate_Valid(t *testing.T) {
+
+	dialects := []Dialect{
+		// The record separator longer than the delimiter.
+		{Delimiter: '|', Quote: '"', RecordSeparator: "||"},
+		// The quote is not used.
+		{Delimiter: ',', Quote: ',', QuoteNone: true},
+		// Paired quotes.
+		{Delimiter: ',', OpenQuote: '「', CloseQuote: '」'},
+	}
+
+	for _, dialect := range dialects {
+		if err := dialect.Validate(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package customcsv
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Encoder writes structs as records to a Writer.
+type Encoder struct {
+	// NoHeader indicates that the header is not written.
+	NoHeader bool
+
+	// NullValue is the value written for nil pointers.
+	// It is set to default empty string by NewEncoder.
+	NullValue string
+
+	w       *Writer
+	t       reflect.Type
+	fields  []structField
+	columns []int
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+// The header derived from the "csv" struct tags is written before the first record.
+func NewEncoder(w *Writer) *Encoder {
+	return &Encoder{
+		w: w,
+	}
+}
+
+// Marshal returns the CSV encoding with a header of the slice of structs v.
+func Marshal(v interface{}) ([]byte, error) {
+
+	var b bytes.Buffer
+	if err := NewEncoder(NewWriter(&b)).EncodeAll(v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// Encode writes the struct v (or a pointer to it) as one record.
+// Before the first record, the header is written.
+func (e *Encoder) Encode(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("encode target must be a struct or a non-nil pointer to a struct")
+	}
+
+	if err := e.prepare(rv.Type()); err != nil {
+		return err
+	}
+
+	return e.encodeRecord(rv)
+}
+
+// EncodeAll writes all the elements of the slice v as records and flushes the Writer.
+// The element type of the slice must be a struct or a pointer to a struct.
+func (e *Encoder) EncodeAll(v interface{}) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return errors.New("encode target must be a slice of structs")
+	}
+
+	structType := rv.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return errors.New("encode target must be a slice of structs")
+	}
+
+	if err := e.prepare(structType); err != nil {
+		return err
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		elem := rv.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				return errors.New("encode target must not contain nil elements")
+			}
+			elem = elem.Elem()
+		}
+
+		if err := e.encodeRecord(elem); err != nil {
+			return err
+		}
+	}
+
+	return e.w.Flush()
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *Encoder) prepare(t reflect.Type) error {
+
+	if e.t == t {
+		return nil
+	}
+
+	if e.t != nil {
+		return errors.New("encode target must be the same type as the previous one")
+	}
+
+	fields, err := structFields(t)
+	if err != nil {
+		return err
+	}
+
+	columns, numColumn, err := structColumns(fields)
+	if err != nil {
+		return err
+	}
+
+	e.t = t
+	e.fields = fields
+	e.columns = make([]int, numColumn)
+	for i := range e.columns {
+		e.columns[i] = -1
+	}
+	for i, column := range columns {
+		e.columns[column] = i
+	}
+
+	if e.NoHeader {
+		return nil
+	}
+
+	header := make([]string, numColumn)
+	for column, i := range e.columns {
+		if i >= 0 {
+			header[column] = fields[i].name
+		}
+	}
+
+	return e.w.Write(header)
+}
+
+func (e *Encoder) encodeRecord(v reflect.Value) error {
+
+	record := make([]string, len(e.columns))
+	for column, i := range e.columns {
+		if i < 0 {
+			continue
+		}
+
+		field := e.fields[i]
+		fv := v.FieldByIndex(field.index)
+
+		if field.omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		s, err := e.formatField(fv, field.layout)
+		if err != nil {
+			return err
+		}
+		record[column] = s
+	}
+
+	return e.w.Write(record)
+}
+
+func (e *Encoder) formatField(v reflect.Value, layout string) (string, error) {
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return e.NullValue, nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(layout), nil
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+
+	return "", fmt.Errorf("unsupported type %v", v.Type())
+}
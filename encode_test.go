@@ -0,0 +1,256 @@
+package customcsv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type lowerText string
+
+func (l lowerText) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(string(l))), nil
+}
+
+func TestMarshal(t *testing.T) {
+
+	type item struct {
+		ID      int       `csv:"id"`
+		Name    string    `csv:"name"`
+		Price   float64   `csv:"price"`
+		Active  bool      `csv:"active"`
+		Created time.Time `csv:"created" layout:"2006-01-02"`
+		Note    *string   `csv:"note"`
+		Code    lowerText `csv:"code"`
+		Ignored string    `csv:"-"`
+	}
+
+	note := "x"
+	items := []item{
+		{ID: 1, Name: "a,b", Price: 1.5, Active: true, Created: time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), Note: &note, Code: "ABC"},
+		{ID: 2, Name: "c", Price: -2, Active: false, Created: time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), Note: nil, Ignored: "z"},
+	}
+
+	result, err := Marshal(items)
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expect := "id,name,price,active,created,note,code\r\n" +
+		"1,\"a,b\",1.5,true,2021-05-01,x,abc\r\n" +
+		"2,c,-2,false,2021-12-31,,\r\n"
+
+	if string(result) != expect {
+		t.Fatal("failed test\n", string(result))
+	}
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+
+	type item struct {
+		ID   int     `csv:"id"`
+		Name string  `csv:"name"`
+		Rate *int    `csv:"rate"`
+		Memo string  `csv:"memo,omitempty"`
+		Size float32 `csv:"size"`
+	}
+
+	rate := 3
+	items := []*item{
+		{ID: 1, Name: "\"a\"\nb", Rate: &rate, Memo: "m", Size: 0.25},
+		{ID: 2, Name: "", Rate: nil, Memo: "", Size: 10},
+	}
+
+	data, err := Marshal(items)
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	var decoded []*item
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(decoded, items) {
+		t.Fatal("failed test\n", string(data))
+	}
+}
+
+func TestEncoder_WriterFormat(t *testing.T) {
+
+	type item struct {
+		A string
+		B string
+	}
+
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	w.Delimiter = ';'
+	w.Quote = '\''
+	w.AllQuotes = true
+	w.RecordSeparator = "\n"
+
+	e := NewEncoder(w)
+	if err := e.Encode(item{A: "1", B: "x'y"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := e.Encode(&item{A: "2", B: ""}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expect := "'A';'B'\n" +
+		"'1';'x''y'\n" +
+		"'2';''\n"
+
+	if b.String() != expect {
+		t.Fatal("failed test\n", b.String())
+	}
+}
+
+func TestEncoder_NoHeader_NullValue(t *testing.T) {
+
+	type item struct {
+		A *int
+		B int `csv:",omitempty"`
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(NewWriter(&b))
+	e.NoHeader = true
+	e.NullValue = "NULL"
+
+	if err := e.EncodeAll([]item{{A: nil, B: 0}, {A: new(int), B: 1}}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expect := "NULL,\r\n" +
+		"0,1\r\n"
+
+	if b.String() != expect {
+		t.Fatal("failed test\n", b.String())
+	}
+}
+
+func TestEncoder_Index(t *testing.T) {
+
+	type item struct {
+		A string `csv:"a,index=2"`
+		B string `csv:"b"`
+		C string `csv:"c,index=0"`
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(NewWriter(&b))
+
+	if err := e.EncodeAll([]item{{A: "1", B: "2", C: "3"}}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expect := "c,b,a\r\n" +
+		"3,2,1\r\n"
+
+	if b.String() != expect {
+		t.Fatal("failed test\n", b.String())
+	}
+}
+
+func TestEncoder_NoHeader_Index_RoundTrip(t *testing.T) {
+
+	// The fields without the index option fill the remaining columns in both Encoder and Decoder.
+	type item struct {
+		A string
+		B string `csv:",index=0"`
+		C string
+		D string `csv:",index=3"`
+	}
+
+	items := []item{
+		{A: "a", B: "b", C: "c", D: "d"},
+		{A: "1", B: "2", C: "3", D: "4"},
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(NewWriter(&b))
+	e.NoHeader = true
+
+	if err := e.EncodeAll(items); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expect := "b,a,c,d\r\n" +
+		"2,1,3,4\r\n"
+
+	if b.String() != expect {
+		t.Fatal("failed test\n", b.String())
+	}
+
+	var decoded []item
+	if err := NewDecoder(NewReader(&b)).DecodeAll(&decoded); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(decoded, items) {
+		t.Fatal("failed test\n", decoded)
+	}
+}
+
+func TestEncoder_Embedded(t *testing.T) {
+
+	type Address struct {
+		City string `csv:"city"`
+		Zip  string `csv:"zip"`
+	}
+	type item struct {
+		ID      int `csv:"id"`
+		Address `csv:"addr_"`
+	}
+
+	result, err := Marshal([]item{{ID: 1, Address: Address{City: "Tokyo", Zip: "100"}}})
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expect := "id,addr_city,addr_zip\r\n" +
+		"1,Tokyo,100\r\n"
+
+	if string(result) != expect {
+		t.Fatal("failed test\n", string(result))
+	}
+}
+
+func TestEncoder_DifferentType(t *testing.T) {
+
+	type item1 struct{ A string }
+	type item2 struct{ B string }
+
+	var b bytes.Buffer
+	e := NewEncoder(NewWriter(&b))
+
+	if err := e.Encode(item1{A: "1"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := e.Encode(item2{B: "1"}); err == nil {
+		t.Fatal("failed test\n")
+	}
+}
+
+func TestMarshal_InvalidTarget(t *testing.T) {
+
+	if _, err := Marshal("abc"); err == nil {
+		t.Fatal("failed test\n")
+	}
+
+	if _, err := Marshal([]string{"a"}); err == nil {
+		t.Fatal("failed test\n")
+	}
+
+	type item struct{ A string }
+	if _, err := Marshal([]*item{nil}); err == nil {
+		t.Fatal("failed test\n")
+	}
+}
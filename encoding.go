@@ -0,0 +1,378 @@
+package customcsv
+
+import (
+	"io"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a character encoding of the input and the output.
+type Encoding interface {
+	// NewDecoder returns a reader that converts the text in the encoding read from r into UTF-8.
+	// Invalid sequences are converted into utf8.RuneError (U+FFFD).
+	NewDecoder(r io.Reader) io.Reader
+
+	// AppendRune appends the bytes of c in the encoding to dst and returns the extended slice.
+	// If c cannot be represented in the encoding, it returns false.
+	AppendRune(dst []byte, c rune) ([]byte, bool)
+}
+
+var (
+	// UTF16LE is UTF-16 in little-endian byte order.
+	// A BOM at the beginning of the input is skipped.
+	UTF16LE Encoding = utf16Encoding{bigEndian: false}
+
+	// UTF16BE is UTF-16 in big-endian byte order.
+	// A BOM at the beginning of the input is skipped.
+	UTF16BE Encoding = utf16Encoding{bigEndian: true}
+
+	// Windows31J is Windows-31J (Microsoft code page 932), the Shift_JIS of Windows.
+	// It includes the NEC special characters, the IBM extensions and the user-defined area (U+E000-U+E757).
+	Windows31J Encoding = windows31JEncoding{}
+
+	// ShiftJIS is the same as Windows31J.
+	ShiftJIS = Windows31J
+
+	// EUCJP is EUC-JP, including JIS X 0212 on decoding.
+	// JIS X 0208 is mapped in the same way as Windows31J. (e.g. 0xA1C1 is U+FF5E)
+	EUCJP Encoding = eucJPEncoding{}
+)
+
+// runeDecoder decodes the rune at the beginning of src, and returns the rune and its size in bytes.
+// If src is incomplete and more bytes may follow, it returns a size of 0.
+type runeDecoder func(src []byte, atEOF bool) (rune, int)
+
+// decodeReader converts the text read from r into UTF-8 by the runeDecoder.
+type decodeReader struct {
+	r      io.Reader
+	decode runeDecoder
+
+	// skipBOM indicates that a BOM (U+FEFF) at the beginning is skipped.
+	skipBOM bool
+
+	// Bytes read from r and not yet decoded.
+	src    []byte
+	srcPos int
+
+	// Bytes decoded and not yet returned.
+	dst    []byte
+	dstPos int
+
+	err error
+}
+
+func newDecodeReader(r io.Reader, decode runeDecoder, skipBOM bool) *decodeReader {
+	return &decodeReader{
+		r:       r,
+		decode:  decode,
+		skipBOM: skipBOM,
+		src:     make([]byte, 0, 4096),
+	}
+}
+
+func (d *decodeReader) Read(p []byte) (int, error) {
+
+	for d.dstPos == len(d.dst) {
+		if d.srcPos == len(d.src) && d.err != nil {
+			return 0, d.err
+		}
+
+		if d.err == nil {
+			// Move the incomplete bytes to the beginning, and read more.
+			d.src = d.src[:copy(d.src, d.src[d.srcPos:])]
+			d.srcPos = 0
+
+			n, err := d.r.Read(d.src[len(d.src):cap(d.src)])
+			d.src = d.src[:len(d.src)+n]
+			d.err = err
+		}
+
+		d.decodeAll()
+	}
+
+	n := copy(p, d.dst[d.dstPos:])
+	d.dstPos += n
+	return n, nil
+}
+
+// decodeAll decodes the complete runes in the source buffer.
+func (d *decodeReader) decodeAll() {
+
+	d.dst = d.dst[:0]
+	d.dstPos = 0
+
+	for d.srcPos < len(d.src) {
+		c, size := d.decode(d.src[d.srcPos:], d.err != nil)
+		if size == 0 {
+			break
+		}
+		d.srcPos += size
+
+		if d.skipBOM {
+			d.skipBOM = false
+			if c == '\uFEFF' {
+				continue
+			}
+		}
+		d.dst = utf8.AppendRune(d.dst, c)
+	}
+}
+
+type utf16Encoding struct {
+	bigEndian bool
+}
+
+func (e utf16Encoding) NewDecoder(r io.Reader) io.Reader {
+	return newDecodeReader(r, e.decodeRune, true)
+}
+
+func (e utf16Encoding) decodeRune(src []byte, atEOF bool) (rune, int) {
+
+	if len(src) < 2 {
+		if atEOF {
+			return utf8.RuneError, len(src)
+		}
+		return 0, 0
+	}
+
+	r1 := e.unit(src)
+	if !utf16.IsSurrogate(r1) {
+		return r1, 2
+	}
+
+	if len(src) < 4 {
+		if atEOF || r1 >= 0xDC00 {
+			return utf8.RuneError, 2
+		}
+		return 0, 0
+	}
+
+	c := utf16.DecodeRune(r1, e.unit(src[2:]))
+	if c == utf8.RuneError {
+		// An unpaired surrogate.
+		return c, 2
+	}
+	return c, 4
+}
+
+// unit returns the code unit at the beginning of src.
+func (e utf16Encoding) unit(src []byte) rune {
+	if e.bigEndian {
+		return rune(src[0])<<8 | rune(src[1])
+	}
+	return rune(src[1])<<8 | rune(src[0])
+}
+
+func (e utf16Encoding) AppendRune(dst []byte, c rune) ([]byte, bool) {
+
+	if !utf8.ValidRune(c) {
+		return dst, false
+	}
+
+	var units []uint16
+	units = utf16.AppendRune(units, c)
+	for _, u := range units {
+		if e.bigEndian {
+			dst = append(dst, byte(u>>8), byte(u))
+		} else {
+			dst = append(dst, byte(u), byte(u>>8))
+		}
+	}
+	return dst, true
+}
+
+type windows31JEncoding struct{}
+
+func (windows31JEncoding) NewDecoder(r io.Reader) io.Reader {
+	return newDecodeReader(r, decodeWindows31J, false)
+}
+
+func decodeWindows31J(src []byte, atEOF bool) (rune, int) {
+
+	b := src[0]
+	switch {
+	case b <= 0x80:
+		return rune(b), 1
+	case 0xA1 <= b && b <= 0xDF:
+		// Half-width katakana.
+		return 0xFF61 + rune(b-0xA1), 1
+	case (0x81 <= b && b <= 0x9F) || (0xE0 <= b && b <= 0xFC):
+		// Double-byte character.
+	default:
+		return utf8.RuneError, 1
+	}
+
+	if len(src) < 2 {
+		if atEOF {
+			return utf8.RuneError, 1
+		}
+		return 0, 0
+	}
+
+	trail := src[1]
+	if trail < 0x40 || trail == 0x7F || trail > 0xFC {
+		// The trail byte is read as the next character.
+		return utf8.RuneError, 1
+	}
+
+	lead := int(b) - 0x81
+	if b >= 0xE0 {
+		lead = int(b) - 0xC1
+	}
+	cell := int(trail) - 0x40
+	if trail > 0x7F {
+		cell = int(trail) - 0x41
+	}
+
+	pointer := lead*188 + cell
+	if pointer < len(jis0208Table) && jis0208Table[pointer] != 0 {
+		return rune(jis0208Table[pointer]), 2
+	}
+	if trail < 0x80 {
+		return utf8.RuneError, 1
+	}
+	return utf8.RuneError, 2
+}
+
+func (windows31JEncoding) AppendRune(dst []byte, c rune) ([]byte, bool) {
+
+	switch {
+	case c < 0x80:
+		return append(dst, byte(c)), true
+	case 0xFF61 <= c && c <= 0xFF9F:
+		return append(dst, byte(c-0xFF61+0xA1)), true
+	}
+
+	pointer, ok := jis0208Pointer(c)
+	if !ok {
+		return dst, false
+	}
+
+	lead, trail := pointer/188, pointer%188
+	if lead < 0x1F {
+		lead += 0x81
+	} else {
+		lead += 0xC1
+	}
+	if trail < 0x3F {
+		trail += 0x40
+	} else {
+		trail += 0x41
+	}
+	return append(dst, byte(lead), byte(trail)), true
+}
+
+type eucJPEncoding struct{}
+
+func (eucJPEncoding) NewDecoder(r io.Reader) io.Reader {
+	return newDecodeReader(r, decodeEUCJP, false)
+}
+
+func decodeEUCJP(src []byte, atEOF bool) (rune, int) {
+
+	b := src[0]
+	size := 2
+	switch {
+	case b < 0x80:
+		return rune(b), 1
+	case b == 0x8F:
+		// JIS X 0212.
+		size = 3
+	case b == 0x8E || (0xA1 <= b && b <= 0xFE):
+	default:
+		return utf8.RuneError, 1
+	}
+
+	if len(src) < size {
+		if atEOF || !isEUCJPTrail(src[1:]) {
+			return utf8.RuneError, 1
+		}
+		return 0, 0
+	}
+
+	if !isEUCJPTrail(src[1:size]) {
+		return utf8.RuneError, 1
+	}
+
+	var c uint16
+	switch {
+	case b == 0x8E:
+		if src[1] > 0xDF {
+			return utf8.RuneError, 2
+		}
+		// Half-width katakana.
+		return 0xFF61 + rune(src[1]-0xA1), 2
+	case b == 0x8F:
+		c = jis0212Table[int(src[1]-0xA1)*94+int(src[2]-0xA1)]
+	default:
+		c = jis0208Table[int(b-0xA1)*94+int(src[1]-0xA1)]
+	}
+
+	if c == 0 {
+		return utf8.RuneError, size
+	}
+	return rune(c), size
+}
+
+// isEUCJPTrail reports whether all the bytes are the trail bytes of EUC-JP.
+func isEUCJPTrail(b []byte) bool {
+	for _, c := range b {
+		if c < 0xA1 || c > 0xFE {
+			return false
+		}
+	}
+	return true
+}
+
+func (eucJPEncoding) AppendRune(dst []byte, c rune) ([]byte, bool) {
+
+	switch {
+	case c < 0x80:
+		return append(dst, byte(c)), true
+	case 0xFF61 <= c && c <= 0xFF9F:
+		return append(dst, 0x8E, byte(c-0xFF61+0xA1)), true
+	}
+
+	pointer, ok := jis0208Pointer(c)
+	if !ok || pointer >= 94*94 {
+		return dst, false
+	}
+
+	return append(dst, byte(pointer/94+0xA1), byte(pointer%94+0xA1)), true
+}
+
+var (
+	jis0208PointersOnce sync.Once
+	jis0208Pointers     map[rune]int
+)
+
+// jis0208Pointer returns the pointer of c in jis0208Table.
+// If c has more than one pointer, the one in JIS X 0208 or the NEC special characters is preferred.
+func jis0208Pointer(c rune) (int, bool) {
+
+	jis0208PointersOnce.Do(func() {
+		jis0208Pointers = make(map[rune]int, len(jis0208Table))
+		for pointer, c := range jis0208Table {
+			if c == 0 {
+				continue
+			}
+			if _, ok := jis0208Pointers[rune(c)]; ok {
+				continue
+			}
+			if 8272 <= pointer && pointer <= 8835 {
+				// The NEC selected IBM extensions, which are duplicated in the IBM extensions.
+				continue
+			}
+			jis0208Pointers[rune(c)] = pointer
+		}
+	})
+
+	if c == 0x2212 {
+		// MINUS SIGN is mapped to FULLWIDTH HYPHEN-MINUS in Windows-31J.
+		c = 0xFF0D
+	}
+
+	pointer, ok := jis0208Pointers[rune(c)]
+	return pointer, ok
+}
@@ -0,0 +1,249 @@
+package customcsv
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRead_Windows31J(t *testing.T) {
+
+	s := "\x82\xA0,\x82\xA2\r\n\"\xB6\xC5,\x81\x60\",\x87\x40\r\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.Encoding = Windows31J
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"あ", "い"},
+		{"ｶﾅ,～", "①"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestRead_Windows31J_Invalid(t *testing.T) {
+
+	// An invalid trail byte is read as the next character.
+	s := "\x82,a\xFD,\x82"
+
+	r := NewReader(strings.NewReader(s))
+	r.Encoding = ShiftJIS
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"�", "a�", "�"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestRead_EUCJP(t *testing.T) {
+
+	s := "\xA4\xA2,\xA4\xA4\r\n\"\x8E\xB6\x8E\xC5,\xA1\xC1\",\x8F\xB0\xA1\r\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.Encoding = EUCJP
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"あ", "い"},
+		{"ｶﾅ,～", "丂"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestRead_UTF16LE_BOM(t *testing.T) {
+
+	s := "\xFF\xFE\x0D\x54\x4D\x52\x2C\x00\x24\x50\x0D\x00\x0A\x00\x61\x00\x2C\x00\x42\xD8\xB7\xDF\x0D\x00\x0A\x00"
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	header, err := r.Header()
+	if err != nil || !reflect.DeepEqual(header, []string{"名前", "値"}) {
+		t.Fatal("failed test\n", header, err)
+	}
+
+	expected := [][]string{
+		{"a", "𠮷"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestRead_UTF16BE_BOM(t *testing.T) {
+
+	s := "\xFE\xFF\x54\x0D\x52\x4D\x00\x2C\x50\x24\x00\x0D\x00\x0A"
+
+	r := NewReader(strings.NewReader(s))
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"名前", "値"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestRead_UTF16LE_NoBOM(t *testing.T) {
+
+	s := "a\x00,\x00\x42\xD8\xB7\xDF\x00\xDC"
+
+	r := NewReader(strings.NewReader(s))
+	r.Encoding = UTF16LE
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// An unpaired surrogate is invalid.
+	expected := [][]string{
+		{"a", "𠮷�"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestDecoder_OneByteReader(t *testing.T) {
+
+	// Multibyte characters split across reads.
+	tests := []struct {
+		encoding Encoding
+		input    string
+		expected string
+	}{
+		{Windows31J, "\x82\xA0\xB6\x87\x40a", "あｶ①a"},
+		{EUCJP, "\xA4\xA2\x8E\xB6\x8F\xB0\xA1a", "あｶ丂a"},
+		{UTF16LE, "\xFF\xFE\x42\xD8\xB7\xDFa\x00", "𠮷a"},
+		{UTF16BE, "\xD8\x42\xDF\xB7\x00a\x00", "𠮷a�"},
+	}
+
+	for _, test := range tests {
+		decoded, err := io.ReadAll(test.encoding.NewDecoder(iotest.OneByteReader(strings.NewReader(test.input))))
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if string(decoded) != test.expected {
+			t.Fatal("failed test\n", test.input, string(decoded))
+		}
+	}
+}
+
+func TestDecoder_Error(t *testing.T) {
+
+	_, err := io.ReadAll(EUCJP.NewDecoder(iotest.ErrReader(errors.New("read error"))))
+	if err == nil || err.Error() != "read error" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestEncoding_AppendRune(t *testing.T) {
+
+	tests := []struct {
+		encoding Encoding
+		input    string
+		expected []byte
+	}{
+		{Windows31J, "aあｶ①～−", []byte("a\x82\xA0\xB6\x87\x40\x81\x60\x81\x7C")},
+		{EUCJP, "aあｶ～", []byte("a\xA4\xA2\x8E\xB6\xA1\xC1")},
+		{UTF16LE, "a𠮷", []byte("a\x00\x42\xD8\xB7\xDF")},
+		{UTF16BE, "a𠮷", []byte("\x00a\xD8\x42\xDF\xB7")},
+	}
+
+	for _, test := range tests {
+		var b []byte
+		for _, c := range test.input {
+			var ok bool
+			b, ok = test.encoding.AppendRune(b, c)
+			if !ok {
+				t.Fatal("failed test\n", test.input, c)
+			}
+		}
+
+		if !bytes.Equal(b, test.expected) {
+			t.Fatalf("failed test\n%s % X", test.input, b)
+		}
+
+		decoded, err := io.ReadAll(test.encoding.NewDecoder(bytes.NewReader(b)))
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		if string(decoded) != strings.ReplaceAll(test.input, "−", "－") {
+			t.Fatal("failed test\n", string(decoded))
+		}
+	}
+}
+
+func TestEncoding_AppendRune_Unrepresentable(t *testing.T) {
+
+	for _, encoding := range []Encoding{Windows31J, EUCJP} {
+		if _, ok := encoding.AppendRune(nil, '😀'); ok {
+			t.Fatal("failed test\n", encoding)
+		}
+	}
+
+	if _, ok := UTF16LE.AppendRune(nil, 0xD800); ok {
+		t.Fatal("failed test\n")
+	}
+}
+
+func TestEncoding_Windows31J_RoundTrip(t *testing.T) {
+
+	// All the characters in the table are encoded into the bytes decoded into the same character.
+	for _, c := range jis0208Table {
+		if c == 0 {
+			continue
+		}
+
+		b, ok := Windows31J.AppendRune(nil, rune(c))
+		if !ok {
+			t.Fatal("failed test\n", c)
+		}
+
+		decoded, _ := decodeWindows31J(b, true)
+		if decoded != rune(c) {
+			t.Fatal("failed test\n", c, decoded)
+		}
+	}
+}
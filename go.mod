@@ -1,3 +1,3 @@
 module github.com/onozaty/go-customcsv
 
-go 1.16
+go 1.23
@@ -0,0 +1,72 @@
+package customcsv
+
+import (
+	"io"
+	"iter"
+)
+
+// All returns an iterator over the remaining records.
+// If an error occurs, it is yielded and the iteration stops.
+//
+//	for record, err := range r.All() {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(record)
+//	}
+func (r *Reader) All() iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if !yield(record, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// TypedReader reads records from a Reader as values of the struct type T.
+type TypedReader[T any] struct {
+	d *Decoder
+}
+
+// NewTypedReader returns a new TypedReader that reads from r.
+// The fields are mapped in the same way as Decoder.
+func NewTypedReader[T any](r *Reader) *TypedReader[T] {
+	return &TypedReader[T]{
+		d: NewDecoder(r),
+	}
+}
+
+// Read reads one record as a value of T.
+// At the end of the input, it returns io.EOF.
+func (t *TypedReader[T]) Read() (T, error) {
+
+	var v T
+	if err := t.d.Decode(&v); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return v, nil
+}
+
+// All returns an iterator over the remaining records as values of T.
+// The records are read one by one, without reading the whole input.
+// If an error occurs, it is yielded and the iteration stops.
+func (t *TypedReader[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := t.Read()
+			if err == io.EOF {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
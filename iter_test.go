@@ -0,0 +1,147 @@
+package customcsv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReader_All(t *testing.T) {
+
+	s := `a,b
+c,d
+`
+
+	r := NewReader(strings.NewReader(s))
+
+	records := [][]string{}
+	for record, err := range r.All() {
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		records = append(records, record)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"c", "d"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestReader_All_Error(t *testing.T) {
+
+	s := `a,b
+c,d,e
+f,g
+`
+
+	r := NewReader(strings.NewReader(s))
+
+	count := 0
+	var lastErr error
+	for _, err := range r.All() {
+		count++
+		lastErr = err
+	}
+
+	// The iteration stops at the error.
+	if count != 2 || lastErr == nil || lastErr.Error() != "parse error on record 2: wrong number of fields (expected 2, actual 3)" {
+		t.Fatal("failed test\n", count, lastErr)
+	}
+}
+
+func TestReader_All_Break(t *testing.T) {
+
+	s := `a
+b
+c
+`
+
+	r := NewReader(strings.NewReader(s))
+
+	for record, err := range r.All() {
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		if record[0] == "a" {
+			break
+		}
+	}
+
+	// The remaining records can still be read.
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"b"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestTypedReader(t *testing.T) {
+
+	s := `id,name
+1,a
+2,b
+`
+
+	type item struct {
+		ID   int    `csv:"id"`
+		Name string `csv:"name"`
+	}
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	tr := NewTypedReader[item](r)
+
+	items := []item{}
+	for v, err := range tr.All() {
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		items = append(items, v)
+	}
+
+	expected := []item{
+		{ID: 1, Name: "a"},
+		{ID: 2, Name: "b"},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Fatal("failed test\n", items)
+	}
+}
+
+func TestTypedReader_Error(t *testing.T) {
+
+	s := `id
+1
+x
+`
+
+	type item struct {
+		ID int `csv:"id"`
+	}
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	tr := NewTypedReader[item](r)
+
+	v, err := tr.Read()
+	if err != nil || v.ID != 1 {
+		t.Fatal("failed test\n", v, err)
+	}
+
+	v, err = tr.Read()
+	if err == nil || err.Error() != `parse error on record 3, column 1: cannot convert "x" to int: invalid syntax` {
+		t.Fatal("failed test\n", err)
+	}
+	if v.ID != 0 {
+		t.Fatal("failed test\n", v)
+	}
+}
@@ -0,0 +1,421 @@
+package customcsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"iter"
+	"runtime"
+	"sync"
+	"unicode/utf8"
+)
+
+// ParallelReader reads records from an io.ReaderAt by parsing chunks of the input concurrently.
+//
+// The input is split into chunks, and the first record boundary in each chunk is found
+// for both cases where the chunk starts outside and inside a quoted field.
+// Which case is correct is resolved by whether the preceding chunk ends inside a quoted field.
+// Then the chunks are parsed concurrently, and the records are returned in the original order.
+//
+// Since the record boundaries are found by counting quotes, the input is read twice,
+// and LazyQuotes and Comment of Reader are not supported.
+type ParallelReader struct {
+	// Delimiter is the field delimiter.
+	// It is set to default comma (',') by NewParallelReader.
+	Delimiter rune
+
+	// DelimiterString is the field delimiter of multiple characters, such as "||".
+	// If specified, it is used instead of Delimiter.
+	DelimiterString string
+
+	// Quote is the field quote character.
+	// It is set to default double quote ('"') by NewParallelReader.
+	Quote rune
+
+	// OpenQuote and CloseQuote are the quote characters at the beginning and the end of a field.
+	// If 0, Quote is used. (default)
+	OpenQuote  rune
+	CloseQuote rune
+
+	// QuoteNone disables quoting. If true, quote characters are read as ordinary characters.
+	QuoteNone bool
+
+	// SpecialRecordSeparator is the special record separator.
+	// If not specified, a newline ('\n' '\r' '\r\n') will be used as the record separator.
+	SpecialRecordSeparator string
+
+	// FieldsPerRecord is the number of expected fields per record.
+	// FieldsPerRecord > 0 : Checks for the specified value.
+	// FieldsPerRecord = 0 : Check by the number of fields in the first record.
+	// FieldsPerRecord < 0 : No check.
+	FieldsPerRecord int
+
+	// ChunkSize is the size of each chunk in bytes.
+	// It is set to default 4 MiB by NewParallelReader.
+	ChunkSize int64
+
+	// Concurrency is the maximum number of chunks parsed concurrently.
+	// It is set to default runtime.GOMAXPROCS(0) by NewParallelReader.
+	Concurrency int
+
+	r    io.ReaderAt
+	size int64
+}
+
+// NewParallelReader returns a new ParallelReader that reads size bytes from r.
+func NewParallelReader(r io.ReaderAt, size int64) *ParallelReader {
+	return &ParallelReader{
+		Delimiter:   ',',
+		Quote:       '"',
+		ChunkSize:   4 << 20,
+		Concurrency: runtime.GOMAXPROCS(0),
+		r:           r,
+		size:        size,
+	}
+}
+
+// ReadAll reads all the records.
+func (p *ParallelReader) ReadAll() ([][]string, error) {
+
+	records := [][]string{}
+
+	for record, err := range p.All() {
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// All returns an iterator over the records in the original order.
+// The record numbers and lines in a ParseError are those in the whole input.
+// If an error occurs, it is yielded and the iteration stops.
+func (p *ParallelReader) All() iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+
+		starts, err := p.chunkStarts()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		fieldsPerRecord, err := p.fieldsPerRecord()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		numChunk := len(starts) - 1
+		results := make([]chan chunkResult, numChunk)
+		for i := range results {
+			results[i] = make(chan chunkResult, 1)
+		}
+
+		// Chunks are parsed ahead up to Concurrency, including the ones not yet yielded.
+		slots := make(chan struct{}, p.concurrency())
+		done := make(chan struct{})
+		defer close(done)
+
+		go func() {
+			for i := 0; i < numChunk; i++ {
+				select {
+				case slots <- struct{}{}:
+				case <-done:
+					return
+				}
+
+				go func(i int) {
+					results[i] <- p.parseChunk(starts[i], starts[i+1], fieldsPerRecord)
+				}(i)
+			}
+		}()
+
+		baseRecord := 0
+		baseLine := 0
+		for i := 0; i < numChunk; i++ {
+			result := <-results[i]
+
+			for _, record := range result.records {
+				if !yield(record, nil) {
+					return
+				}
+			}
+
+			if result.err != nil {
+				if pe, ok := result.err.(*ParseError); ok {
+					pe.Record += baseRecord
+					pe.StartLine += baseLine
+					pe.Line += baseLine
+				}
+				yield(nil, result.err)
+				return
+			}
+
+			baseRecord += len(result.records)
+			baseLine += result.lines
+			<-slots
+		}
+	}
+}
+
+type chunkResult struct {
+	records [][]string
+	lines   int
+	err     error
+}
+
+// parseChunk parses the records that start in [start, end).
+func (p *ParallelReader) parseChunk(start int64, end int64, fieldsPerRecord int) chunkResult {
+
+	r := p.newReader(start)
+	r.FieldsPerRecord = fieldsPerRecord
+
+	result := chunkResult{}
+	for r.InputOffset() < end {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.err = err
+			break
+		}
+		result.records = append(result.records, record)
+	}
+
+	// The number of lines in the chunk.
+	result.lines = r.line - 1
+	return result
+}
+
+func (p *ParallelReader) newReader(start int64) *Reader {
+
+	var r *Reader
+	section := io.NewSectionReader(p.r, start, p.size-start)
+	if start == 0 {
+		// The BOM is skipped only at the beginning of the input.
+		r = NewReader(section)
+	} else {
+		r = newReader(bufio.NewReader(section), start)
+	}
+
+	r.Delimiter = p.Delimiter
+	r.DelimiterString = p.DelimiterString
+	r.Quote = p.Quote
+	r.OpenQuote = p.OpenQuote
+	r.CloseQuote = p.CloseQuote
+	r.QuoteNone = p.QuoteNone
+	r.SpecialRecordSeparator = p.SpecialRecordSeparator
+	return r
+}
+
+func (p *ParallelReader) fieldsPerRecord() (int, error) {
+
+	if p.FieldsPerRecord != 0 {
+		return p.FieldsPerRecord, nil
+	}
+
+	// Check by the number of fields in the first record.
+	record, err := p.newReader(0).Read()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(record), nil
+}
+
+func (p *ParallelReader) concurrency() int {
+
+	if p.Concurrency < 1 {
+		return 1
+	}
+	return p.Concurrency
+}
+
+// chunkScan is the result of scanning a chunk.
+type chunkScan struct {
+	// quoting indicates whether the chunk ends inside a quoted field,
+	// if the chunk starts outside ([0]) and inside ([1]) a quoted field.
+	quoting [2]bool
+
+	// starts are the offsets of the first record start in the chunk,
+	// if the chunk starts outside ([0]) and inside ([1]) a quoted field. -1 if not found.
+	starts [2]int64
+
+	err error
+}
+
+// chunkStarts returns the offsets of the first record start in each chunk.
+// The last element is the size of the input.
+func (p *ParallelReader) chunkStarts() ([]int64, error) {
+
+	chunkSize := p.ChunkSize
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	numChunk := int((p.size + chunkSize - 1) / chunkSize)
+	if numChunk <= 1 {
+		return []int64{0, p.size}, nil
+	}
+
+	scans := make([]chunkScan, numChunk)
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, p.concurrency())
+	for i := 0; i < numChunk; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			scans[i] = p.scanChunk(int64(i)*chunkSize, min(int64(i+1)*chunkSize, p.size))
+			<-slots
+		}(i)
+	}
+	wg.Wait()
+
+	starts := make([]int64, numChunk+1)
+	starts[numChunk] = p.size
+
+	// Whether the chunk starts inside a quoted field, which is where the preceding chunk ends.
+	quoting := 0
+	for i := 0; i < numChunk; i++ {
+		if scans[i].err != nil {
+			return nil, scans[i].err
+		}
+
+		if i > 0 {
+			starts[i] = scans[i].starts[quoting]
+		}
+
+		if scans[i].quoting[quoting] {
+			quoting = 1
+		} else {
+			quoting = 0
+		}
+	}
+
+	for i := numChunk - 1; i > 0; i-- {
+		if starts[i] == -1 {
+			// No record starts in the chunk.
+			starts[i] = starts[i+1]
+		}
+	}
+
+	return starts, nil
+}
+
+// scanChunk finds the first record start in [start, end), and whether the chunk ends inside a quoted field,
+// for both cases where the chunk starts outside and inside a quoted field.
+func (p *ParallelReader) scanChunk(start int64, end int64) chunkScan {
+
+	// In valid input, outside a quoted field, the opening quote starts a quoted field,
+	// and the closing quote is the second of a doubled closing quote, which is an escaped quote.
+	// Inside a quoted field, the closing quote ends it, and the opening quote is a literal.
+	var openQuote, closeQuote []byte
+	if !p.QuoteNone {
+		openRune, closeRune := p.Quote, p.Quote
+		if p.OpenQuote != 0 {
+			openRune = p.OpenQuote
+		}
+		if p.CloseQuote != 0 {
+			closeRune = p.CloseQuote
+		}
+		openQuote = utf8.AppendRune(nil, openRune)
+		closeQuote = utf8.AppendRune(nil, closeRune)
+	}
+	separator := []byte(p.SpecialRecordSeparator)
+
+	// Read ahead so that the format characters starting in the chunk can be matched.
+	data := make([]byte, end-start+int64(max(utf8.UTFMax, len(separator), 2)))
+	n, err := p.r.ReadAt(data, start)
+	if err != nil && err != io.EOF {
+		return chunkScan{err: err}
+	}
+	data = data[:n]
+	length := min(int(end-start), n)
+
+	// The first bytes of the quotes and the record separators.
+	var candidate [256]bool
+	for _, quote := range [][]byte{openQuote, closeQuote} {
+		if len(quote) != 0 {
+			candidate[quote[0]] = true
+		}
+	}
+	if len(separator) != 0 {
+		candidate[separator[0]] = true
+	} else {
+		candidate['\n'] = true
+		candidate['\r'] = true
+	}
+
+	// Both cases are scanned at once. Once they are in the same state, they remain the same.
+	scan := chunkScan{quoting: [2]bool{false, true}, starts: [2]int64{-1, -1}}
+
+	for i := 0; i < length; {
+		if !candidate[data[i]] {
+			i++
+			continue
+		}
+
+		if len(closeQuote) != 0 && bytes.HasPrefix(data[i:], closeQuote) {
+			// The closing quote switches between inside and outside a quoted field.
+			for c := range scan.quoting {
+				scan.quoting[c] = !scan.quoting[c]
+			}
+			i += len(closeQuote)
+			continue
+		}
+
+		if len(openQuote) != 0 && bytes.HasPrefix(data[i:], openQuote) {
+			for c := range scan.quoting {
+				scan.quoting[c] = true
+			}
+			i += len(openQuote)
+			continue
+		}
+
+		n := matchSeparator(data[i:], separator)
+		if n == 0 {
+			i++
+			continue
+		}
+
+		for c, quoting := range scan.quoting {
+			if !quoting && scan.starts[c] == -1 {
+				scan.starts[c] = start + int64(i+n)
+			}
+		}
+		i += n
+	}
+
+	return scan
+}
+
+// matchSeparator returns the length of the record separator at the beginning of b, otherwise 0.
+func matchSeparator(b []byte, separator []byte) int {
+
+	if len(separator) != 0 {
+		if bytes.HasPrefix(b, separator) {
+			return len(separator)
+		}
+		return 0
+	}
+
+	switch b[0] {
+	case '\n':
+		return 1
+	case '\r':
+		if len(b) >= 2 && b[1] == '\n' {
+			return 2
+		}
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,334 @@
+package customcsv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parallelInput() string {
+
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&b, "%d,abc,\"x\ny\"\r\n", i)
+		case 1:
+			fmt.Fprintf(&b, "%d,\"a\"\"b\",あいう\n", i)
+		case 2:
+			fmt.Fprintf(&b, "%d,\"\"\"\",\"c,\r\n\"\"d\"\"\"\r", i)
+		default:
+			fmt.Fprintf(&b, "%d,,\n", i)
+		}
+	}
+	return b.String()
+}
+
+func TestParallelReader_ReadAll(t *testing.T) {
+
+	s := parallelInput()
+
+	expected, err := NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	for _, chunkSize := range []int64{1, 2, 3, 7, 16, 100, 1 << 20} {
+		for _, concurrency := range []int{1, 4} {
+			p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+			p.ChunkSize = chunkSize
+			p.Concurrency = concurrency
+
+			records, err := p.ReadAll()
+			if err != nil {
+				t.Fatal("failed test\n", chunkSize, concurrency, err)
+			}
+
+			if !reflect.DeepEqual(records, expected) {
+				t.Fatal("failed test\n", chunkSize, concurrency, records)
+			}
+		}
+	}
+}
+
+func TestParallelReader_ReadAll_WithBOM(t *testing.T) {
+
+	s := "\uFEFFa,b\r\nc,d\r\n"
+
+	p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+	p.ChunkSize = 2
+
+	records, err := p.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"c", "d"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestParallelReader_ReadAll_SpecialRecordSeparator(t *testing.T) {
+
+	s := "a\tb|\"|\"\tc\nd|e\t\"f\"\"|\"|"
+
+	expected := [][]string{
+		{"a", "b"},
+		{"|", "c\nd"},
+		{"e", "f\"|"},
+	}
+
+	for _, chunkSize := range []int64{1, 2, 3, 5, 100} {
+		p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+		p.Delimiter = '\t'
+		p.SpecialRecordSeparator = "|"
+		p.ChunkSize = chunkSize
+
+		records, err := p.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", chunkSize, err)
+		}
+
+		if !reflect.DeepEqual(records, expected) {
+			t.Fatal("failed test\n", chunkSize, records)
+		}
+	}
+}
+
+func TestParallelReader_ReadAll_Quote(t *testing.T) {
+
+	s := "'a,b',c\n'd\n''e''',f\n"
+
+	expected := [][]string{
+		{"a,b", "c"},
+		{"d\n'e'", "f"},
+	}
+
+	for _, chunkSize := range []int64{1, 2, 3, 100} {
+		p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+		p.Quote = '\''
+		p.ChunkSize = chunkSize
+
+		records, err := p.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", chunkSize, err)
+		}
+
+		if !reflect.DeepEqual(records, expected) {
+			t.Fatal("failed test\n", chunkSize, records)
+		}
+	}
+}
+
+func TestParallelReader_ReadAll_OpenCloseQuote(t *testing.T) {
+
+	s := "「a,b」,c\n「d\n」」「e」,f\n"
+
+	expected := [][]string{
+		{"a,b", "c"},
+		{"d\n」「e", "f"},
+	}
+
+	for _, chunkSize := range []int64{1, 2, 3, 100} {
+		p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+		p.OpenQuote = '「'
+		p.CloseQuote = '」'
+		p.ChunkSize = chunkSize
+
+		records, err := p.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", chunkSize, err)
+		}
+
+		if !reflect.DeepEqual(records, expected) {
+			t.Fatal("failed test\n", chunkSize, records)
+		}
+	}
+}
+
+func TestParallelReader_ReadAll_OpenQuoteInQuotedField(t *testing.T) {
+
+	// The opening quotes in a quoted field are literals, and do not end it.
+	s := "「a「b\nc」,x\nd,e\n「「」」f「」,g\n"
+
+	expected, err := func() ([][]string, error) {
+		r := NewReader(strings.NewReader(s))
+		r.OpenQuote = '「'
+		r.CloseQuote = '」'
+		return r.ReadAll()
+	}()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(expected, [][]string{{"a「b\nc", "x"}, {"d", "e"}, {"「」f「", "g"}}) {
+		t.Fatal("failed test\n", expected)
+	}
+
+	for chunkSize := int64(1); chunkSize <= int64(len(s)); chunkSize++ {
+		p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+		p.OpenQuote = '「'
+		p.CloseQuote = '」'
+		p.ChunkSize = chunkSize
+
+		records, err := p.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", chunkSize, err)
+		}
+
+		if !reflect.DeepEqual(records, expected) {
+			t.Fatal("failed test\n", chunkSize, records)
+		}
+	}
+}
+
+func TestParallelReader_ReadAll_QuoteNone(t *testing.T) {
+
+	s := "\"a,b\n\"c,d\"\n"
+
+	expected := [][]string{
+		{"\"a", "b"},
+		{"\"c", "d\""},
+	}
+
+	for _, chunkSize := range []int64{1, 2, 3, 100} {
+		p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+		p.QuoteNone = true
+		p.ChunkSize = chunkSize
+
+		records, err := p.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", chunkSize, err)
+		}
+
+		if !reflect.DeepEqual(records, expected) {
+			t.Fatal("failed test\n", chunkSize, records)
+		}
+	}
+}
+
+func TestParallelReader_ReadAll_Empty(t *testing.T) {
+
+	p := NewParallelReader(strings.NewReader(""), 0)
+
+	records, err := p.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if len(records) != 0 {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestParallelReader_ReadAll_FieldsPerRecord(t *testing.T) {
+
+	s := "a,b\n\"c\nd\",e\nf\ng,h\n"
+
+	p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+	p.ChunkSize = 4
+
+	_, err := p.ReadAll()
+
+	pe := &ParseError{}
+	if !errors.As(err, &pe) {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The record number and lines are those in the whole input.
+	if !errors.Is(err, ErrFieldCount) || pe.Record != 3 || pe.StartLine != 4 || pe.Line != 4 || pe.ByteOffset != 12 {
+		t.Fatal("failed test\n", pe, pe.StartLine, pe.Line, pe.ByteOffset)
+	}
+
+	if err.Error() != "parse error on record 3: wrong number of fields (expected 2, actual 1)" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestParallelReader_ReadAll_FieldsPerRecord_Negative(t *testing.T) {
+
+	s := "a,b\nc\nd,e,f\n"
+
+	p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+	p.FieldsPerRecord = -1
+	p.ChunkSize = 3
+
+	records, err := p.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"c"},
+		{"d", "e", "f"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestParallelReader_ReadAll_QuoteNotClosed(t *testing.T) {
+
+	s := "a,b\nc,d\ne,\"f\n"
+
+	p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+	p.ChunkSize = 4
+
+	_, err := p.ReadAll()
+	if !errors.Is(err, ErrQuoteNotClosed) {
+		t.Fatal("failed test\n", err)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Record != 3 || pe.StartLine != 3 {
+		t.Fatal("failed test\n", pe, pe.StartLine)
+	}
+}
+
+func TestParallelReader_All_Break(t *testing.T) {
+
+	s := parallelInput()
+
+	p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+	p.ChunkSize = 8
+	p.Concurrency = 2
+
+	count := 0
+	for _, err := range p.All() {
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		count++
+		if count == 10 {
+			break
+		}
+	}
+
+	if count != 10 {
+		t.Fatal("failed test\n", count)
+	}
+}
+
+func BenchmarkParallelReader_ReadAll(b *testing.B) {
+
+	s := benchmarkInput()
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		p := NewParallelReader(strings.NewReader(s), int64(len(s)))
+		p.ChunkSize = 64 << 10
+		if _, err := p.ReadAll(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,15 +2,40 @@ package customcsv
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 type ParseError struct {
+	// Message is the description of the error.
 	Message string
-	Record  int
-	Column  int
+
+	// Record is the record number where the error occurred. (1-based)
+	Record int
+
+	// Column is the field number where the error occurred. (1-based, 0 if not specific to a field)
+	Column int
+
+	// StartLine is the line where the record starts. (1-based)
+	StartLine int
+
+	// Line is the line where the error occurred. (1-based)
+	Line int
+
+	// ByteOffset is the byte offset in the input where the error occurred.
+	ByteOffset int64
+
+	// Err is the underlying error, if any.
+	Err error
+
+	// Raw is the text of the skipped record. It is set only when passed to ErrorHandler.
+	Raw string
 }
 
 func (e *ParseError) Error() string {
@@ -21,15 +46,130 @@ func (e *ParseError) Error() string {
 	}
 }
 
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrQuoteNotClosed is the error that the quote is not closed before EOF.
+	ErrQuoteNotClosed = errors.New("quote is not closed")
+
+	// ErrBareQuote is the error that a quote appears in a non quoted field.
+	ErrBareQuote = errors.New("bare quote in non quoted field")
+
+	// ErrUnescapedQuote is the error that a non doubled quote appears in a quoted field.
+	ErrUnescapedQuote = errors.New("unescaped quote in quoted field")
+
+	// ErrFieldCount is the error that the number of fields is different from FieldsPerRecord.
+	// The ParseError wraps FieldCountError, which has the expected and actual numbers.
+	ErrFieldCount = errors.New("wrong number of fields")
+
+	// ErrEmptyHeader is the error that the header has an empty name.
+	ErrEmptyHeader = errors.New("empty header name")
+
+	// ErrDuplicateHeader is the error that the header has duplicate names.
+	ErrDuplicateHeader = errors.New("duplicate header name")
+
+	// ErrTooManyErrors is the error that the number of errors exceeds MaxErrors.
+	ErrTooManyErrors = errors.New("too many errors")
+
+	// ErrNoHeader is returned when header access is requested while HasHeader is false.
+	ErrNoHeader = errors.New("header is not enabled")
+
+	// ErrInvalidUTF8 is the error that a field has invalid UTF-8, when InvalidUTF8 is InvalidUTF8Error.
+	ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+	// ErrControlChar is the error that a field has a control character, when ControlChars is ControlCharError.
+	ErrControlChar = errors.New("control character in field")
+
+	// ErrLimitExceeded is the error that the input exceeds one of the limits, such as MaxFieldSize.
+	// The ParseError wraps LimitError, which has the name and the value of the limit.
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// InvalidUTF8Policy is the policy for invalid UTF-8 in the fields read by Reader.
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8Replace replaces each invalid byte with U+FFFD.
+	InvalidUTF8Replace InvalidUTF8Policy = iota
+
+	// InvalidUTF8Error reports a ParseError wrapping ErrInvalidUTF8 at the invalid byte.
+	InvalidUTF8Error
+
+	// InvalidUTF8Keep passes the invalid bytes through as they are.
+	InvalidUTF8Keep
+)
+
+// ControlCharPolicy is the policy for the C0 control characters other than tab, CR and LF
+// in the fields read by Reader.
+type ControlCharPolicy int
+
+const (
+	// ControlCharKeep reads the control characters as they are.
+	ControlCharKeep ControlCharPolicy = iota
+
+	// ControlCharError reports a ParseError wrapping ErrControlChar at the control character.
+	ControlCharError
+
+	// ControlCharStrip removes the control characters from the fields.
+	ControlCharStrip
+)
+
+// FieldCountError is the error that the number of fields is different from the expected number.
+type FieldCountError struct {
+	Expected int
+	Actual   int
+}
+
+func (e *FieldCountError) Error() string {
+	return fmt.Sprintf("%v (expected %d, actual %d)", ErrFieldCount, e.Expected, e.Actual)
+}
+
+func (e *FieldCountError) Unwrap() error {
+	return ErrFieldCount
+}
+
+// LimitError is the error that the input exceeds one of the limits of Reader.
+type LimitError struct {
+	// Limit is the name of the limit, such as "MaxFieldSize".
+	Limit string
+
+	// Max is the value of the limit.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v (%s %d)", ErrLimitExceeded, e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
 type Reader struct {
 	// Delimiter is the field delimiter.
 	// It is set to default comma (',') by NewReader.
 	Delimiter rune
 
+	// DelimiterString is the field delimiter of multiple characters, such as "||".
+	// If specified, it is used instead of Delimiter.
+	// If both the delimiter and the record separator match, the longer one is used.
+	DelimiterString string
+
 	// Quote is the field quote character.
 	// It is set to default double quote ('"') by NewReader.
 	Quote rune
 
+	// OpenQuote and CloseQuote are the quote characters at the beginning and the end of a field,
+	// such as '「' and '」'. If 0, Quote is used. (default)
+	// A CloseQuote in a quoted field is escaped by doubling it.
+	OpenQuote  rune
+	CloseQuote rune
+
+	// QuoteNone disables quoting. If true, quote characters are read as ordinary characters.
+	QuoteNone bool
+
 	// SpecialRecordSeparator is the special record separator.
 	// If not specified, a newline ('\n' '\r' '\r\n') will be used as the record separator.
 	SpecialRecordSeparator string
@@ -40,117 +180,1009 @@ type Reader struct {
 	// FieldsPerRecord < 0 : No check.
 	FieldsPerRecord int
 
-	r          *bufio.Reader
-	runeBuffer []rune
-	numRecord  int
+	// LazyQuotes relaxes the quote rules.
+	// If true, a quote may appear in a non quoted field, a non doubled quote may appear in a quoted field,
+	// and whitespace may appear between the closing quote and the delimiter.
+	LazyQuotes bool
+
+	// Escape is the escape character, such as '\\'.
+	// The character following the Escape character is read literally,
+	// even if it is the delimiter, the quote, the record separator or the Escape character itself.
+	// If 0, escapes are not used. (default)
+	Escape rune
+
+	// EscapeSequences indicates that "\n", "\t", "\r" and "\0" following the Escape character
+	// are decoded to a newline, a tab, a carriage return and a NUL.
+	EscapeSequences bool
+
+	// Comment is the comment character.
+	// Lines beginning with the Comment character are skipped.
+	// If 0, comments are not used. (default)
+	Comment rune
+
+	// SkipLines is the number of lines to skip before reading.
+	// The skipped lines are not parsed.
+	SkipLines int
+
+	// SkipUntil is called for each record after SkipLines.
+	// Records are skipped until it returns true. The record for which it returns true is not skipped.
+	// If nil, no records are skipped. (default)
+	SkipUntil func(record []string) bool
+
+	// ErrorHandler is called for each ParseError of a record.
+	// If it returns nil, the record is skipped and reading continues from the next record.
+	// The rest of the record is skipped following the quotes, so a newline in a quoted field does not end it.
+	// If it returns an error, Read returns the error.
+	// If nil, Read returns the ParseError. (default)
+	ErrorHandler func(err *ParseError) error
+
+	// MaxErrors is the maximum number of errors passed to ErrorHandler.
+	// If exceeded, Read returns an error wrapping ErrTooManyErrors and the ParseError.
+	// If 0, there is no limit. (default)
+	MaxErrors int
+
+	// ReuseRecord controls whether calls to Read may return a slice sharing the backing array
+	// of the previous call's returned slice for performance.
+	// If true, the returned slice is valid only until the next call of Read. (default: false)
+	ReuseRecord bool
+
+	// HasHeader indicates that the first record is a header.
+	// If true, the first record is read as the header and is not returned by Read.
+	HasHeader bool
+
+	// Encoding is the character encoding of the input, such as Windows31J.
+	// The input is converted into UTF-8 before parsing, and the offsets, such as InputOffset
+	// and ByteOffset of ParseError, are those in the converted text.
+	// If nil, the input is UTF-8, or UTF-16 if it begins with the UTF-16 BOM. (default)
+	Encoding Encoding
+
+	// InvalidUTF8 is the policy for invalid UTF-8 in the fields.
+	// It is set to default InvalidUTF8Replace by NewReader.
+	// The fields returned by ReadBytes are not replaced.
+	InvalidUTF8 InvalidUTF8Policy
+
+	// ControlChars is the policy for the C0 control characters (U+0000-U+001F) other than tab, CR and LF
+	// in the fields, including those decoded from EscapeSequences.
+	// It is set to default ControlCharKeep by NewReader.
+	ControlChars ControlCharPolicy
+
+	// MaxFieldSize is the maximum size of a field in bytes, after unquoting.
+	// MaxRecordSize is the maximum size of a record in bytes, which is the total of the fields.
+	// MaxFieldsPerRecord is the maximum number of fields per record.
+	// If exceeded, Read stops reading there and returns a ParseError wrapping ErrLimitExceeded.
+	// The error is not passed to ErrorHandler, and the next Read skips the rest of the record.
+	// If 0, there is no limit. (default)
+	MaxFieldSize       int
+	MaxRecordSize      int
+	MaxFieldsPerRecord int
+
+	// MaxRecords is the maximum number of records, including the header.
+	// If exceeded, Read returns a ParseError wrapping ErrLimitExceeded for each of the following records,
+	// without parsing them.
+	// The error is not passed to ErrorHandler.
+	// If 0, there is no limit. (default)
+	MaxRecords int
+
+	r           *bufio.Reader
+	numRecord   int
+	header      []string
+	headerIndex map[string]int
+
+	// headerErr is the error reading the header, which is returned by all the following reads.
+	headerErr error
+
+	preambleSkipped bool
+	pending         bool
+
+	// resync indicates that the rest of the record exceeding a limit is skipped by the next read.
+	resync bool
+
+	// skipState is the quote state at the last parse error, from which the rest of the record is skipped.
+	skipState quoteState
+
+	// Bytes buffered in r and the position of the next byte to read in them.
+	buf    []byte
+	bufPos int
+	format byteFormat
+
+	// Record being parsed. Each field ends at the index in fieldIndexes.
+	recordBuffer []byte
+	fieldIndexes []int
+
+	// Slices returned by the previous call, to be reused.
+	lastRecord      []string
+	lastBytesRecord [][]byte
+
+	// Position of the next byte to read.
+	line     int
+	col      int
+	offset   int64
+	lastByte byte
+
+	recordPos      position
+	fieldPositions []position
+
+	// Positions of the bytes in the record buffer, only while the fields are checked for errors.
+	// Each is the position in the input of the byte at the index and the following bytes.
+	bufferPositions []bufferPosition
+
+	raw      []byte
+	numError int
+}
+
+// position is a position in the input.
+type position struct {
+	line   int
+	col    int
+	offset int64
+}
+
+// bufferPosition is the position in the input of the byte at the index in the record buffer.
+type bufferPosition struct {
+	index int
+	pos   position
 }
 
-var utf8bom = []byte{0xEF, 0xBB, 0xBF}
+// quoteState is the state of the quotes in the field being parsed.
+type quoteState struct {
+	// quotedField indicates that the field begins with a quote.
+	quotedField bool
+
+	// quoting indicates that the position is between the quotes.
+	quoting bool
+
+	// fieldStart indicates that nothing of the field has been read yet.
+	fieldStart bool
+}
+
+var (
+	utf8bom    = []byte{0xEF, 0xBB, 0xBF}
+	utf16leBOM = []byte{0xFF, 0xFE}
+	utf16beBOM = []byte{0xFE, 0xFF}
+)
 
 func NewReader(r io.Reader) *Reader {
 
 	br := bufio.NewReader(r)
 	mark, err := br.Peek(len(utf8bom))
 
+	offset := int64(0)
 	if err == nil {
 		if reflect.DeepEqual(mark, utf8bom) {
 			// If there is a BOM, skip the BOM.
 			br.Discard(len(utf8bom))
+			offset = int64(len(utf8bom))
 		}
 	}
 
+	return newReader(br, offset)
+}
+
+// newReader returns a new Reader that reads from br, which starts at the offset in the input.
+func newReader(br *bufio.Reader, offset int64) *Reader {
 	return &Reader{
-		Delimiter:  ',',
-		Quote:      '"',
-		r:          br,
-		runeBuffer: []rune{},
-		numRecord:  1,
+		Delimiter: ',',
+		Quote:     '"',
+		r:         br,
+		numRecord: 1,
+		line:      1,
+		offset:    offset,
 	}
 }
 
 func (r *Reader) Read() ([]string, error) {
 
+	if err := r.readDataRecord(); err != nil {
+		return nil, err
+	}
+
+	return r.recordStrings(r.ReuseRecord), nil
+}
+
+// ReadBytes reads one record and returns the fields as byte slices.
+// Unlike Read, the fields are not copied. They refer to the internal buffer of the Reader,
+// and are valid only until the next call of a read method. Invalid UTF-8 is not replaced.
+// The returned slice is also reused by the next call.
+func (r *Reader) ReadBytes() ([][]byte, error) {
+
+	if err := r.readDataRecord(); err != nil {
+		return nil, err
+	}
+
+	return r.recordBytes(), nil
+}
+
+// readDataRecord reads the header if not yet read, and then one record into the record buffer.
+func (r *Reader) readDataRecord() error {
+
+	if r.HasHeader && r.header == nil {
+		if _, err := r.Header(); err != nil {
+			return err
+		}
+	}
+
+	return r.readRecord()
+}
+
+// Header returns the header record.
+// If the header has not been read yet, the first record is read as the header.
+// If the header cannot be read, the error is returned by this and all the following reads,
+// so that a data record is not read as the header.
+func (r *Reader) Header() ([]string, error) {
+
+	if !r.HasHeader {
+		return nil, ErrNoHeader
+	}
+
+	if r.header == nil && r.headerErr == nil {
+		r.header, r.headerIndex, r.headerErr = r.readHeader()
+	}
+	return r.header, r.headerErr
+}
+
+// readHeader reads the first record as the header.
+func (r *Reader) readHeader() ([]string, map[string]int, error) {
+
+	if err := r.readRecord(); err != nil {
+		return nil, nil, err
+	}
+
+	record := r.recordStrings(false)
+	index := make(map[string]int, len(record))
+	for i, name := range record {
+		if name == "" {
+			return nil, nil, r.fieldError(i, ErrEmptyHeader.Error(), ErrEmptyHeader)
+		}
+		if _, ok := index[name]; ok {
+			err := fmt.Errorf("%w %q", ErrDuplicateHeader, name)
+			return nil, nil, r.fieldError(i, err.Error(), err)
+		}
+		index[name] = i
+	}
+
+	return record, index, nil
+}
+
+// HeaderIndex returns the index of the column with the specified header name.
+// If there is no such column, it returns -1.
+func (r *Reader) HeaderIndex(name string) (int, error) {
+
+	if _, err := r.Header(); err != nil {
+		return -1, err
+	}
+
+	i, ok := r.headerIndex[name]
+	if !ok {
+		return -1, nil
+	}
+	return i, nil
+}
+
+// ReadRecord reads one record and returns it with access to the fields by header name.
+func (r *Reader) ReadRecord() (*Record, error) {
+
+	if !r.HasHeader {
+		return nil, ErrNoHeader
+	}
+
+	fields, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Record{Fields: fields, index: r.headerIndex}, nil
+}
+
+// ReadMap reads one record and returns it as a map keyed by header name.
+func (r *Reader) ReadMap() (map[string]string, error) {
+
+	record, err := r.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	return record.Map(), nil
+}
+
+// readRecord reads one record into the record buffer.
+func (r *Reader) readRecord() error {
+
+	if !r.preambleSkipped {
+		r.preambleSkipped = true
+		r.setupEncoding()
+		if err := r.skipPreamble(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		err := r.readNextRecord()
+
+		pe, ok := err.(*ParseError)
+		if !ok {
+			return err
+		}
+
+		// The record with the error is skipped, and the next Read starts from the next record.
+		r.numRecord++
+
+		if r.ErrorHandler == nil || errors.Is(pe, ErrLimitExceeded) {
+			// Errors of the limits are not passed to ErrorHandler, so as not to read the rest of the input.
+			return pe
+		}
+
+		pe.Raw = r.rawRecord()
+
+		r.numError++
+		if r.MaxErrors > 0 && r.numError > r.MaxErrors {
+			return fmt.Errorf("%w: %w", ErrTooManyErrors, pe)
+		}
+
+		if err := r.ErrorHandler(pe); err != nil {
+			return err
+		}
+	}
+}
+
+// setupEncoding converts the input into UTF-8 by the Encoding before the first read.
+func (r *Reader) setupEncoding() {
+
+	encoding := r.Encoding
+	if encoding == nil && r.offset == 0 {
+		// UTF-16 is detected by the BOM, which is skipped by the decoder.
+		mark, _ := r.r.Peek(len(utf16leBOM))
+		if bytes.Equal(mark, utf16leBOM) {
+			encoding = UTF16LE
+		} else if bytes.Equal(mark, utf16beBOM) {
+			encoding = UTF16BE
+		}
+	}
+
+	if encoding != nil {
+		r.r = bufio.NewReader(encoding.NewDecoder(r.r))
+	}
+}
+
+func (r *Reader) readNextRecord() error {
+
+	if r.resync {
+		// Skip the rest of the record with the error of a limit.
+		err := r.skipRecord()
+		r.resync = false
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.pending {
+		// The record has already been parsed by SkipUntil.
+		r.pending = false
+	} else {
+		if r.MaxRecords > 0 && r.numRecord > r.MaxRecords {
+			// The record is not parsed, and is skipped by the next read.
+			return r.recordsLimitError()
+		}
+
+		if err := r.parseRecord(); err != nil {
+			if _, ok := err.(*ParseError); ok {
+				if errors.Is(err, ErrLimitExceeded) {
+					// The rest of the record may be huge, so it is skipped by the next read.
+					r.resync = true
+					return err
+				}
+
+				// Resynchronize at the end of the record.
+				if err := r.skipRecord(); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+	}
+
+	if err := r.checkFields(); err != nil {
+		// The whole record has been parsed, so the next record follows.
+		return err
+	}
+
+	if err := r.verifyRecord(len(r.fieldIndexes)); err != nil {
+		return err
+	}
+	r.numRecord++
+	return nil
+}
+
+// recordsLimitError returns the error of MaxRecords for the next record without parsing it.
+// If there is no next record, it returns io.EOF.
+func (r *Reader) recordsLimitError() error {
+
+	if err := r.setup(); err != nil {
+		return err
+	}
+
+	if r.bufPos == len(r.buf) {
+		if err := r.fill(); err != nil {
+			return err
+		}
+	}
+
+	r.recordPos = r.position()
+	r.skipState = quoteState{fieldStart: true}
+	r.resync = true
+	return r.parseError(0, &LimitError{Limit: "MaxRecords", Max: r.MaxRecords}, r.recordPos)
+}
+
+// rawRecord returns the text of the record most recently parsed, without the record separator.
+func (r *Reader) rawRecord() string {
+
+	raw := string(r.raw)
+
+	if r.SpecialRecordSeparator != "" {
+		return strings.TrimSuffix(raw, r.SpecialRecordSeparator)
+	}
+
+	if strings.HasSuffix(raw, "\r\n") {
+		return raw[:len(raw)-2]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+}
+
+func (r *Reader) skipPreamble() error {
+
+	for i := 0; i < r.SkipLines; i++ {
+		if err := r.skipLine(); err != nil {
+			return err
+		}
+	}
+
+	if r.SkipUntil != nil {
+		for {
+			err := r.parseRecord()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if r.SkipUntil(r.recordStrings(false)) {
+				// The record is not skipped, and is returned as the first record.
+				r.pending = true
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipLine skips bytes up to the next record separator without parsing.
+func (r *Reader) skipLine() error {
+
+	if err := r.setup(); err != nil {
+		return err
+	}
+
+	for {
+		if r.bufPos == len(r.buf) {
+			if err := r.fill(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+
+		// Skip the bytes that cannot start a record separator.
+		i := r.bufPos
+		for i < len(r.buf) && !r.format.special[r.buf[i]] {
+			i++
+		}
+		if i != r.bufPos {
+			r.consumePlain(i - r.bufPos)
+			continue
+		}
+
+		n, err := r.matchRecordSeparator()
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			r.consume(n)
+			return nil
+		}
+		r.consume(1)
+	}
+}
+
+// skipRecord skips the rest of the record after a parse error without storing the fields.
+// It scans from the quote state at the error, so a record separator in a quoted field does not end the record.
+func (r *Reader) skipRecord() error {
+
+	state := r.skipState
+	spaces := false
+
+	for {
+		if r.bufPos == len(r.buf) {
+			if err := r.fill(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		}
+
+		special := &r.format.special
+		if state.quoting {
+			special = &r.format.quotedSpecial
+		}
+
+		if !special[r.buf[r.bufPos]] && (!state.quotedField || state.quoting) {
+			i := r.bufPos + 1
+			for i < len(r.buf) && !special[r.buf[i]] {
+				i++
+			}
+			r.consumePlain(i - r.bufPos)
+			state.fieldStart = false
+			continue
+		}
+
+		if len(r.format.escape) != 0 && (!state.quotedField || state.quoting) {
+			n, err := r.matchBytes(r.format.escape)
+			if err != nil {
+				return err
+			}
+
+			if n > 0 {
+				r.consume(n)
+				next, err := r.peek(utf8.UTFMax)
+				if err != nil {
+					return err
+				}
+				if len(next) != 0 {
+					_, size := utf8.DecodeRune(next)
+					r.consume(size)
+				}
+				state.fieldStart = false
+				continue
+			}
+		}
+
+		n, err := r.matchBytes(r.format.delimiter)
+		if err != nil {
+			return err
+		}
+		if !state.quoting {
+			m, err := r.matchRecordSeparator()
+			if err != nil {
+				return err
+			}
+
+			if m > 0 && m >= n {
+				r.consume(m)
+				return nil
+			}
+		}
+
+		if n > 0 {
+			r.consume(n)
+			if !state.quoting {
+				state = quoteState{fieldStart: true}
+				spaces = false
+			}
+			continue
+		}
+
+		if state.quotedField {
+			n, err = r.matchBytes(r.format.closeQuote)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				r.consume(n)
+				switch {
+				case state.quoting:
+					state.quoting = false
+				case spaces:
+					// The previous quote was not a closing quote. (LazyQuotes only)
+					spaces = false
+				default:
+					// Escaped quote.
+					state.quoting = true
+				}
+				continue
+			}
+		} else if state.fieldStart {
+			n, err = r.matchBytes(r.format.quote)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				r.consume(n)
+				state = quoteState{quotedField: true, quoting: true}
+				continue
+			}
+		}
+
+		b := r.buf[r.bufPos]
+		if state.quotedField && !state.quoting {
+			switch {
+			case !r.LazyQuotes:
+				// The rest of the field is read as not quoted.
+				state.quotedField = false
+			case b == ' ' || b == '\t':
+				// Whitespace between the closing quote and the delimiter.
+				spaces = true
+				r.consume(1)
+				continue
+			default:
+				// The previous quote was not a closing quote.
+				spaces = false
+				state.quoting = true
+			}
+		}
+
+		// Other bytes, including bare quotes, are skipped one by one.
+		r.consume(1)
+		state.fieldStart = false
+	}
+}
+
+// FieldPos returns the line and column of the start of the field with the given index
+// in the record most recently returned by Read.
+// Lines and columns are 1-based, and columns are counted in bytes.
+func (r *Reader) FieldPos(field int) (line, column int) {
+
+	if field < 0 || field >= len(r.fieldPositions) {
+		panic("out of range index passed to FieldPos")
+	}
+
+	pos := r.fieldPositions[field]
+	return pos.line, pos.col
+}
+
+// InputOffset returns the byte offset of the current reader position in the input.
+func (r *Reader) InputOffset() int64 {
+	return r.offset
+}
+
+// parseRecord parses one record into the record buffer.
+func (r *Reader) parseRecord() error {
+
+	if err := r.setup(); err != nil {
+		return err
+	}
+
 	quotedField := false
 	quoting := false
-	field := []rune{}
-	record := []string{}
+	fieldStart := 0
+	var spaces []byte
+
+	r.recordBuffer = r.recordBuffer[:0]
+	r.fieldIndexes = r.fieldIndexes[:0]
+	r.recordPos = r.position()
+	r.fieldPositions = append(r.fieldPositions[:0], r.recordPos)
+	r.raw = r.raw[:0]
+	r.bufferPositions = r.bufferPositions[:0]
+	trackPositions := r.InvalidUTF8 == InvalidUTF8Error || r.ControlChars == ControlCharError
+	limited := r.MaxFieldSize > 0 || r.MaxRecordSize > 0
 
 	for {
 
-		c, err := r.readRune()
-		if err != nil && err != io.EOF {
-			return nil, err
+		if limited {
+			// Check the bytes appended in the previous iteration, so as not to read the rest of a huge field.
+			if err := r.checkSize(fieldStart); err != nil {
+				r.skipState = quoteState{quotedField: quotedField, quoting: quoting, fieldStart: !quotedField && len(r.recordBuffer) == fieldStart}
+				return err
+			}
 		}
 
-		if err == io.EOF {
+		if r.bufPos == len(r.buf) {
+			if err := r.fill(); err != nil {
+				if err != io.EOF {
+					return err
+				}
 
-			if quoting {
-				return nil, &ParseError{Message: "quote is not closed", Record: r.numRecord, Column: len(record) + 1}
+				if quoting {
+					return r.parseError(len(r.fieldIndexes)+1, ErrQuoteNotClosed, r.position())
+				}
+
+				if len(r.fieldIndexes) == 0 && len(r.recordBuffer) == 0 {
+					return err
+				}
+
+				r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+				return nil
+			}
+		}
+
+		if trackPositions {
+			// The bytes appended in this iteration are read from the current position.
+			r.bufferPositions = append(r.bufferPositions, bufferPosition{index: len(r.recordBuffer), pos: r.position()})
+		}
+
+		special := &r.format.special
+		if quoting {
+			special = &r.format.quotedSpecial
+		}
+
+		if !special[r.buf[r.bufPos]] && (!quotedField || quoting) {
+			// Fast path: Copy the bytes up to the next special byte as they are.
+			i := r.bufPos + 1
+			for i < len(r.buf) && !special[r.buf[i]] {
+				i++
+			}
+			r.recordBuffer = append(r.recordBuffer, r.buf[r.bufPos:i]...)
+			r.consumePlain(i - r.bufPos)
+			continue
+		}
+
+		pos := r.position()
+
+		if len(r.format.comment) != 0 && len(r.fieldIndexes) == 0 && len(r.recordBuffer) == 0 && !quotedField {
+			n, err := r.matchBytes(r.format.comment)
+			if err != nil {
+				return err
 			}
 
-			if len(record) == 0 && len(field) == 0 {
-				return nil, err
+			if n > 0 {
+				// Skip the comment line.
+				r.consume(n)
+				if err := r.skipLine(); err != nil {
+					return err
+				}
+				r.recordPos = r.position()
+				r.fieldPositions[0] = r.recordPos
+				r.raw = r.raw[:0]
+				continue
+			}
+		}
+
+		if len(r.format.escape) != 0 && (!quotedField || quoting) {
+			n, err := r.matchBytes(r.format.escape)
+			if err != nil {
+				return err
 			}
 
-			record = append(record, string(field))
-			if err := r.verifyRecord(record); err != nil {
-				return nil, err
+			if n > 0 {
+				r.consume(n)
+				if err := r.readEscaped(); err != nil {
+					return err
+				}
+				continue
 			}
-			r.numRecord++
-			return record, nil
 		}
 
 		// Judge the record separator first.
+		// If the delimiter also matches, the longer one is used.
+		n, err := r.matchBytes(r.format.delimiter)
+		if err != nil {
+			return err
+		}
 		if !quoting {
-			isRecordSeparator, err := r.judgeRecordSeparator(c)
+			m, err := r.matchRecordSeparator()
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			if isRecordSeparator {
-				record = append(record, string(field))
-				if err := r.verifyRecord(record); err != nil {
-					return nil, err
-				}
-				r.numRecord++
-				return record, nil
+			if m > 0 && m >= n {
+				r.consume(m)
+				r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+				return nil
 			}
 		}
 
-		switch c {
-		case r.Delimiter:
+		if n > 0 {
+			r.consume(n)
 			if quoting {
-				field = append(field, c)
+				r.recordBuffer = append(r.recordBuffer, r.format.delimiter...)
 			} else {
-				record = append(record, string(field))
-				field = []rune{}
+				if r.MaxFieldsPerRecord > 0 && len(r.fieldIndexes)+1 >= r.MaxFieldsPerRecord {
+					r.skipState = quoteState{fieldStart: true}
+					return r.parseError(len(r.fieldIndexes)+2, &LimitError{Limit: "MaxFieldsPerRecord", Max: r.MaxFieldsPerRecord}, pos)
+				}
+				r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+				r.fieldPositions = append(r.fieldPositions, r.position())
+				fieldStart = len(r.recordBuffer)
 				quotedField = false
 				quoting = false
+				spaces = spaces[:0]
 			}
-		case r.Quote:
-			if !quotedField && len(field) == 0 {
-				quotedField = true
-				quoting = true
-			} else {
-				if !quotedField {
-					return nil, &ParseError{Message: "bare quote in non quoted field", Record: r.numRecord, Column: len(record) + 1}
-				}
+			continue
+		}
 
-				if !quoting {
+		if quotedField {
+			n, err = r.matchBytes(r.format.closeQuote)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				r.consume(n)
+				switch {
+				case quoting:
+					quoting = false
+				case len(spaces) != 0:
+					// The previous quote was not a closing quote. (LazyQuotes only)
+					r.recordBuffer = append(append(r.recordBuffer, r.format.closeQuote...), spaces...)
+					spaces = spaces[:0]
+				default:
 					// Escaped quote.
-					field = append(field, c)
+					r.recordBuffer = append(r.recordBuffer, r.format.closeQuote...)
+					quoting = true
+				}
+				continue
+			}
+		} else {
+			quote := r.format.quote
+			n, err = r.matchBytes(quote)
+			if err == nil && n == 0 {
+				quote = r.format.closeQuote
+				n, err = r.matchBytes(quote)
+			}
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				r.consume(n)
+				if len(r.recordBuffer) == fieldStart && bytes.Equal(quote, r.format.quote) {
+					quotedField = true
+					quoting = true
+					continue
 				}
 
-				quoting = !quoting
+				if !r.LazyQuotes {
+					r.skipState = quoteState{}
+					return r.parseError(len(r.fieldIndexes)+1, ErrBareQuote, pos)
+				}
+				// Keep the bare quote as it is.
+				r.recordBuffer = append(r.recordBuffer, quote...)
+				continue
 			}
-		default:
-			if quotedField && !quoting {
-				return nil, &ParseError{Message: "unescaped quote in quoted field", Record: r.numRecord, Column: len(record) + 1}
+		}
+
+		b := r.buf[r.bufPos]
+		if quotedField && !quoting {
+			if !r.LazyQuotes {
+				r.skipState = quoteState{quotedField: true}
+				return r.parseError(len(r.fieldIndexes)+1, ErrUnescapedQuote, pos)
+			}
+
+			if b == ' ' || b == '\t' {
+				// Whitespace between the closing quote and the delimiter is ignored.
+				spaces = append(spaces, b)
+				r.consume(1)
+				continue
+			}
+
+			// The previous quote was not a closing quote.
+			r.recordBuffer = append(append(r.recordBuffer, r.format.closeQuote...), spaces...)
+			spaces = spaces[:0]
+			quoting = true
+		}
+
+		r.recordBuffer = append(r.recordBuffer, b)
+		r.consume(1)
+	}
+}
+
+// checkSize checks the size of the current field, which starts at fieldStart, and the record.
+func (r *Reader) checkSize(fieldStart int) error {
+
+	if r.MaxFieldSize > 0 && len(r.recordBuffer)-fieldStart > r.MaxFieldSize {
+		return r.parseError(len(r.fieldIndexes)+1, &LimitError{Limit: "MaxFieldSize", Max: r.MaxFieldSize}, r.position())
+	}
+
+	if r.MaxRecordSize > 0 && len(r.recordBuffer) > r.MaxRecordSize {
+		return r.parseError(len(r.fieldIndexes)+1, &LimitError{Limit: "MaxRecordSize", Max: r.MaxRecordSize}, r.position())
+	}
+
+	return nil
+}
+
+// readEscaped reads the character following the Escape character into the record buffer.
+func (r *Reader) readEscaped() error {
+
+	next, err := r.peek(utf8.UTFMax)
+	if err != nil {
+		return err
+	}
+
+	if len(next) == 0 {
+		// The Escape character at the end of the input is kept as it is.
+		r.recordBuffer = append(r.recordBuffer, r.format.escape...)
+		return nil
+	}
+
+	if r.EscapeSequences {
+		if c, ok := escapeSequences[next[0]]; ok {
+			r.recordBuffer = append(r.recordBuffer, c)
+			r.consume(1)
+			return nil
+		}
+	}
+
+	_, size := utf8.DecodeRune(next)
+	r.recordBuffer = append(r.recordBuffer, next[:size]...)
+	r.consume(size)
+	return nil
+}
+
+// checkFields checks the fields in the record buffer for invalid UTF-8 and control characters,
+// and strips the control characters if ControlChars is ControlCharStrip.
+func (r *Reader) checkFields() error {
+
+	if r.InvalidUTF8 != InvalidUTF8Error && r.ControlChars == ControlCharKeep {
+		return nil
+	}
+
+	stripped := 0
+	field := 0
+	for i := 0; i < len(r.recordBuffer); {
+		for i >= r.fieldIndexes[field] {
+			r.fieldIndexes[field] -= stripped
+			field++
+		}
+
+		b := r.recordBuffer[i]
+		size := 1
+		if b >= utf8.RuneSelf {
+			if r.InvalidUTF8 == InvalidUTF8Error {
+				c, n := utf8.DecodeRune(r.recordBuffer[i:r.fieldIndexes[field]])
+				if c == utf8.RuneError && n == 1 {
+					return r.parseError(field+1, ErrInvalidUTF8, r.bufferPosition(i))
+				}
+				size = n
+			}
+		} else if b < 0x20 && b != '\t' && b != '\r' && b != '\n' {
+			switch r.ControlChars {
+			case ControlCharError:
+				return r.parseError(field+1, ErrControlChar, r.bufferPosition(i))
+			case ControlCharStrip:
+				stripped++
+				i++
+				continue
 			}
+		}
 
-			field = append(field, c)
+		if stripped != 0 {
+			copy(r.recordBuffer[i-stripped:], r.recordBuffer[i:i+size])
 		}
+		i += size
+	}
+
+	for ; field < len(r.fieldIndexes); field++ {
+		r.fieldIndexes[field] -= stripped
+	}
+	r.recordBuffer = r.recordBuffer[:len(r.recordBuffer)-stripped]
+
+	return nil
+}
+
+// bufferPosition returns the position in the input of the byte at the index in the record buffer.
+func (r *Reader) bufferPosition(index int) position {
+
+	// The last one at or before the index.
+	i := sort.Search(len(r.bufferPositions), func(i int) bool {
+		return r.bufferPositions[i].index > index
+	}) - 1
+	if i < 0 {
+		return r.recordPos
 	}
+
+	bp := r.bufferPositions[i]
+	pos := bp.pos
+	pos.col += index - bp.index
+	pos.offset += int64(index - bp.index)
+	return pos
+}
+
+// escapeSequences is the characters represented by the escape sequences.
+var escapeSequences = map[byte]byte{
+	'n': '\n',
+	't': '\t',
+	'r': '\r',
+	'0': 0,
 }
 
 func (r *Reader) ReadAll() ([][]string, error) {
@@ -158,94 +1190,350 @@ func (r *Reader) ReadAll() ([][]string, error) {
 	records := [][]string{}
 
 	for {
-		record, err := r.Read()
+		// The records are not reused, even if ReuseRecord is true.
+		err := r.readDataRecord()
 		if err == io.EOF {
 			return records, nil
 		}
 		if err != nil {
 			return nil, err
 		}
-		records = append(records, record)
+		records = append(records, r.recordStrings(false))
 	}
 }
 
-func (r *Reader) readRune() (rune, error) {
+// recordStrings returns the fields in the record buffer as strings.
+// The fields share a single string to reduce allocations.
+// If reuse is true, the slice returned by the previous call is reused.
+func (r *Reader) recordStrings(reuse bool) []string {
 
-	if len(r.runeBuffer) != 0 {
-		c := r.runeBuffer[0]
-		r.runeBuffer = r.runeBuffer[1:]
-		return c, nil
+	line := string(r.recordBuffer)
+	valid := r.InvalidUTF8 == InvalidUTF8Keep || utf8.ValidString(line)
+
+	var record []string
+	if reuse && cap(r.lastRecord) >= len(r.fieldIndexes) {
+		record = r.lastRecord[:len(r.fieldIndexes)]
+	} else {
+		record = make([]string, len(r.fieldIndexes))
 	}
 
-	c, _, err := r.r.ReadRune()
-	return c, err
+	start := 0
+	for i, end := range r.fieldIndexes {
+		record[i] = line[start:end]
+		if !valid {
+			// Invalid bytes are replaced with U+FFFD, one for each byte.
+			record[i] = string([]rune(record[i]))
+		}
+		start = end
+	}
+
+	if reuse {
+		r.lastRecord = record
+	}
+	return record
 }
 
-func (r *Reader) peekRune(n int) ([]rune, error) {
+// recordBytes returns the fields in the record buffer as byte slices referring to the buffer.
+func (r *Reader) recordBytes() [][]byte {
 
-	for len(r.runeBuffer) < n {
-		c, _, err := r.r.ReadRune()
-		if err != nil && err != io.EOF {
-			return nil, err
+	record := r.lastBytesRecord[:0]
+
+	start := 0
+	for _, end := range r.fieldIndexes {
+		// Limit the capacity so that appending to a field does not overwrite the next field.
+		record = append(record, r.recordBuffer[start:end:end])
+		start = end
+	}
+
+	r.lastBytesRecord = record
+	return record
+}
+
+// byteFormat is the format characters encoded in bytes.
+type byteFormat struct {
+	// config is the format characters from which the bytes were prepared.
+	config formatConfig
+
+	delimiter  []byte
+	quote      []byte
+	closeQuote []byte
+	comment    []byte
+	escape     []byte
+	separator  []byte
+
+	// err is the error of Validate for the format characters.
+	err error
+
+	// special marks the bytes that may start a format character or a newline.
+	// quotedSpecial is the same for a quoted field, where only the closing quote and the escape are format characters.
+	special       [256]bool
+	quotedSpecial [256]bool
+}
+
+// formatConfig is the format characters specified in Reader.
+type formatConfig struct {
+	delimiter       rune
+	delimiterString string
+	quote           rune
+	openQuote       rune
+	closeQuote      rune
+	quoteNone       bool
+	comment         rune
+	escape          rune
+	separator       string
+}
+
+// setup prepares the format in bytes, if the format characters have been changed.
+// It returns the error of Validate for the format characters.
+func (r *Reader) setup() error {
+
+	config := formatConfig{
+		delimiter:       r.Delimiter,
+		delimiterString: r.DelimiterString,
+		quote:           r.Quote,
+		openQuote:       r.OpenQuote,
+		closeQuote:      r.CloseQuote,
+		quoteNone:       r.QuoteNone,
+		comment:         r.Comment,
+		escape:          r.Escape,
+		separator:       r.SpecialRecordSeparator,
+	}
+
+	f := &r.format
+	if f.delimiter != nil && f.config == config {
+		return f.err
+	}
+
+	*f = byteFormat{
+		config:     config,
+		err:        r.Validate(),
+		delimiter:  utf8.AppendRune(nil, r.Delimiter),
+		quote:      utf8.AppendRune(nil, r.Quote),
+		closeQuote: utf8.AppendRune(nil, r.Quote),
+		separator:  []byte(r.SpecialRecordSeparator),
+	}
+	if r.DelimiterString != "" {
+		f.delimiter = []byte(r.DelimiterString)
+	}
+	if r.OpenQuote != 0 {
+		f.quote = utf8.AppendRune(nil, r.OpenQuote)
+	}
+	if r.CloseQuote != 0 {
+		f.closeQuote = utf8.AppendRune(nil, r.CloseQuote)
+	}
+	if r.QuoteNone {
+		f.quote = nil
+		f.closeQuote = nil
+	}
+	if r.Comment != 0 {
+		f.comment = utf8.AppendRune(nil, r.Comment)
+	}
+	if r.Escape != 0 {
+		f.escape = utf8.AppendRune(nil, r.Escape)
+	}
+
+	f.special['\r'] = true
+	f.special['\n'] = true
+	for _, b := range [][]byte{f.delimiter, f.quote, f.closeQuote, f.comment, f.escape, f.separator} {
+		if len(b) != 0 {
+			f.special[b[0]] = true
 		}
+	}
 
-		if err == io.EOF {
-			// Peek does not error on EOF. It returns only what it can read.
-			return r.runeBuffer, nil
+	f.quotedSpecial['\r'] = true
+	f.quotedSpecial['\n'] = true
+	for _, b := range [][]byte{f.closeQuote, f.escape} {
+		if len(b) != 0 {
+			f.quotedSpecial[b[0]] = true
 		}
+	}
+
+	return f.err
+}
+
+// Validate checks that the format characters do not conflict with each other.
+// It returns an error wrapping ErrInvalidDialect, which lists all the conflicts.
+// It is also checked by the first Read, and after the format characters have been changed.
+func (r *Reader) Validate() error {
+
+	d := Dialect{
+		Delimiter:       r.Delimiter,
+		DelimiterString: r.DelimiterString,
+		Quote:           r.Quote,
+		OpenQuote:       r.OpenQuote,
+		CloseQuote:      r.CloseQuote,
+		QuoteNone:       r.QuoteNone,
+		Escape:          r.Escape,
+		RecordSeparator: r.SpecialRecordSeparator,
+		Comment:         r.Comment,
+	}
+	return d.Validate()
+}
+
+// fill discards the consumed bytes and buffers the next bytes.
+func (r *Reader) fill() error {
 
-		r.runeBuffer = append(r.runeBuffer, c)
+	if _, err := r.r.Discard(r.bufPos); err != nil {
+		return err
 	}
+	r.bufPos = 0
 
-	return r.runeBuffer[0:n], nil
+	if _, err := r.r.Peek(1); err != nil {
+		r.buf = nil
+		return err
+	}
+
+	r.buf, _ = r.r.Peek(r.r.Buffered())
+	return nil
 }
 
-func (r *Reader) judgeRecordSeparator(c rune) (bool, error) {
+// peek returns the buffered bytes from the current position, with at least n bytes unless EOF.
+func (r *Reader) peek(n int) ([]byte, error) {
 
-	if r.SpecialRecordSeparator == "" {
-		// Newlines are record separators.
-		if c == '\n' {
-			return true, nil
+	if len(r.buf)-r.bufPos < n {
+		if _, err := r.r.Discard(r.bufPos); err != nil {
+			return nil, err
 		}
+		r.bufPos = 0
 
-		if c == '\r' {
-			next, err := r.peekRune(1)
-			if err != nil {
-				return false, err
-			}
+		if _, err := r.r.Peek(n); err != nil && err != io.EOF {
+			return nil, err
+		}
+		r.buf, _ = r.r.Peek(r.r.Buffered())
+	}
 
-			if len(next) != 0 && next[0] == '\n' {
-				// Use CR+LF as a single separator
-				r.readRune()
-			}
+	return r.buf[r.bufPos:], nil
+}
+
+// matchBytes returns the length of b if the bytes from the current position start with b, otherwise 0.
+func (r *Reader) matchBytes(b []byte) (int, error) {
+
+	if len(b) == 0 || r.buf[r.bufPos] != b[0] {
+		return 0, nil
+	}
+
+	if len(b) == 1 {
+		return 1, nil
+	}
+
+	next, err := r.peek(len(b))
+	if err != nil {
+		return 0, err
+	}
+
+	if bytes.HasPrefix(next, b) {
+		return len(b), nil
+	}
+	return 0, nil
+}
 
-			return true, nil
+// matchRecordSeparator returns the length of the record separator at the current position, otherwise 0.
+func (r *Reader) matchRecordSeparator() (int, error) {
+
+	if len(r.format.separator) != 0 {
+		// The specified characters are the record separator.
+		return r.matchBytes(r.format.separator)
+	}
+
+	// Newlines are record separators.
+	switch r.buf[r.bufPos] {
+	case '\n':
+		return 1, nil
+	case '\r':
+		next, err := r.peek(2)
+		if err != nil {
+			return 0, err
 		}
 
-	} else {
-		// The specified character is the record separator.
-		if c == []rune(r.SpecialRecordSeparator)[0] {
-			// If the first character is the same, the remaining characters are included in the comparison.
-			remaining, err := r.peekRune(len(r.SpecialRecordSeparator) - 1)
-			if err != nil {
-				return false, err
-			}
+		if len(next) >= 2 && next[1] == '\n' {
+			// Use CR+LF as a single separator
+			return 2, nil
+		}
+		return 1, nil
+	}
 
-			if string(append([]rune{c}, remaining...)) == r.SpecialRecordSeparator {
-				for i := 1; i < len(r.SpecialRecordSeparator); i++ {
-					// Skip characters that are record separators.
-					r.readRune()
-				}
+	return 0, nil
+}
 
-				return true, nil
-			}
+// consume advances the current position by n bytes.
+func (r *Reader) consume(n int) {
+
+	b := r.buf[r.bufPos : r.bufPos+n]
+	for _, c := range b {
+		switch {
+		case c == '\r', c == '\n' && r.lastByte != '\r':
+			r.line++
+			r.col = 0
+		case c == '\n':
+			// LF of CR+LF. The line has already been counted by CR.
+			r.col = 0
+		default:
+			r.col++
 		}
+		r.lastByte = c
 	}
 
-	return false, nil
+	r.advance(b)
 }
 
-func (r *Reader) verifyRecord(record []string) error {
+// consumePlain advances the current position by n bytes that contain no newlines.
+func (r *Reader) consumePlain(n int) {
+
+	b := r.buf[r.bufPos : r.bufPos+n]
+	r.col += n
+	r.lastByte = b[n-1]
+
+	r.advance(b)
+}
+
+func (r *Reader) advance(b []byte) {
+
+	if r.ErrorHandler != nil && !r.resync {
+		// The rest of the record exceeding a limit is not kept.
+		r.raw = append(r.raw, b...)
+	}
+
+	r.offset += int64(len(b))
+	r.bufPos += len(b)
+}
+
+func (r *Reader) position() position {
+	return position{line: r.line, col: r.col + 1, offset: r.offset}
+}
+
+// parseError returns a ParseError that occurred at the position while parsing the current record.
+func (r *Reader) parseError(column int, err error, pos position) *ParseError {
+	return &ParseError{
+		Message:    err.Error(),
+		Record:     r.numRecord,
+		Column:     column,
+		StartLine:  r.recordPos.line,
+		Line:       pos.line,
+		ByteOffset: pos.offset,
+		Err:        err,
+	}
+}
+
+// fieldError returns a ParseError for the field in the record most recently returned by Read.
+func (r *Reader) fieldError(field int, message string, err error) *ParseError {
+
+	pos := r.recordPos
+	if field < len(r.fieldPositions) {
+		pos = r.fieldPositions[field]
+	}
+
+	return &ParseError{
+		Message:    message,
+		Record:     r.numRecord - 1,
+		Column:     field + 1,
+		StartLine:  r.recordPos.line,
+		Line:       pos.line,
+		ByteOffset: pos.offset,
+		Err:        err,
+	}
+}
+
+func (r *Reader) verifyRecord(numField int) error {
 
 	if r.FieldsPerRecord < 0 {
 		// No check.
@@ -254,13 +1542,45 @@ func (r *Reader) verifyRecord(record []string) error {
 
 	if r.FieldsPerRecord == 0 {
 		// Keep the number of fields in the first record.
-		r.FieldsPerRecord = len(record)
+		r.FieldsPerRecord = numField
 		return nil
 	}
 
-	if len(record) != r.FieldsPerRecord {
-		return &ParseError{Message: "wrong number of fields", Record: r.numRecord}
+	if numField != r.FieldsPerRecord {
+		return r.parseError(0, &FieldCountError{Expected: r.FieldsPerRecord, Actual: numField}, r.recordPos)
 	}
 
 	return nil
 }
+
+// Record is a record read with a header.
+// Fields can be accessed by header name.
+type Record struct {
+	Fields []string
+	index  map[string]int
+}
+
+// Get returns the field value of the column with the specified header name.
+// If there is no such column, ok is false.
+func (r *Record) Get(name string) (value string, ok bool) {
+
+	i, ok := r.index[name]
+	if !ok || i >= len(r.Fields) {
+		return "", false
+	}
+
+	return r.Fields[i], true
+}
+
+// Map returns the fields as a map keyed by header name.
+func (r *Record) Map() map[string]string {
+
+	m := make(map[string]string, len(r.index))
+	for name, i := range r.index {
+		if i < len(r.Fields) {
+			m[name] = r.Fields[i]
+		}
+	}
+
+	return m
+}
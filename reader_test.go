@@ -1,10 +1,14 @@
 package customcsv
 
 import (
+	"encoding/csv"
+	"errors"
 	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode/utf8"
 )
 
 func TestNewReader(t *testing.T) {
@@ -346,6 +350,72 @@ func TestNewReader_SpecialRecordSeparator_MultiChar(t *testing.T) {
 	}
 }
 
+func TestNewReader_SpecialRecordSeparator_NonAscii(t *testing.T) {
+
+	tests := []struct {
+		separator string
+		input     string
+		expected  [][]string
+	}{
+		{
+			separator: "¶\n",
+			input:     "a¶,b\nc¶\n\"¶\n\",¶¶\n¶\n",
+			expected:  [][]string{{"a¶", "b\nc"}, {"¶\n", "¶"}, {""}},
+		},
+		{
+			separator: "␞",
+			input:     "あ,␟␞\"␞\",い\n␞",
+			expected:  [][]string{{"あ", "␟"}, {"␞", "い\n"}},
+		},
+		{
+			// A separator whose first byte is shared with other characters.
+			separator: "ア",
+			input:     "イ,ウアエ,オ",
+			expected:  [][]string{{"イ", "ウ"}, {"エ", "オ"}},
+		},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.input))
+		r.SpecialRecordSeparator = test.separator
+		r.FieldsPerRecord = -1
+
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", test.separator, err)
+		}
+
+		if !reflect.DeepEqual(records, test.expected) {
+			t.Fatal("failed test\n", test.separator, records)
+		}
+	}
+}
+
+func TestNewReader_UnitRecordSeparator(t *testing.T) {
+
+	s := "a,b\x1f\"c\nd\"\x1f\"e\x1ff\"\x1e" +
+		"\"g\x1eh\"\x1f\x1e"
+
+	r := NewReader(strings.NewReader(s))
+	r.Delimiter = '\x1f'
+	r.SpecialRecordSeparator = "\x1e"
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a,b", "c\nd", "e\x1ff"},
+		{"g\x1eh", ""},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
 func TestNewReader_Delimiter(t *testing.T) {
 
 	s := "a\tb\n" +
@@ -384,6 +454,77 @@ func TestNewReader_Delimiter(t *testing.T) {
 	}
 }
 
+func TestNewReader_DelimiterString(t *testing.T) {
+
+	tests := []struct {
+		delimiter string
+		separator string
+		input     string
+		expected  [][]string
+	}{
+		{
+			delimiter: "||",
+			input:     "a||b|c||\"d||e\"\n|||f||\n",
+			expected:  [][]string{{"a", "b|c", "d||e"}, {"", "|f", ""}},
+		},
+		{
+			delimiter: "~|~",
+			input:     "a~|~b~|c~~|~\"~|~\"\r\n",
+			expected:  [][]string{{"a", "b~|c~", "~|~"}},
+		},
+		{
+			delimiter: "\t\t",
+			input:     "a\t\tb\tc\t\t\t\n",
+			expected:  [][]string{{"a", "b\tc", "\t"}},
+		},
+		{
+			// The longer delimiter is used rather than the record separator.
+			delimiter: "||",
+			separator: "|",
+			input:     "a||b|c||d|",
+			expected:  [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{
+			// The longer record separator is used rather than the delimiter.
+			delimiter: "|",
+			separator: "||",
+			input:     "a|b||c|d||",
+			expected:  [][]string{{"a", "b"}, {"c", "d"}},
+		},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.input))
+		r.DelimiterString = test.delimiter
+		r.SpecialRecordSeparator = test.separator
+		r.FieldsPerRecord = -1
+
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", test.delimiter, err)
+		}
+
+		if !reflect.DeepEqual(records, test.expected) {
+			t.Fatal("failed test\n", test.delimiter, records)
+		}
+	}
+}
+
+func TestNewReader_DelimiterString_FieldPos(t *testing.T) {
+
+	r := NewReader(strings.NewReader("ab||c||d\n"))
+	r.DelimiterString = "||"
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	line, column := r.FieldPos(2)
+	if line != 1 || column != 8 {
+		t.Fatal("failed test\n", line, column)
+	}
+}
+
 func TestNewReader_Quote(t *testing.T) {
 
 	s := `'a','b'
@@ -423,6 +564,213 @@ func TestNewReader_Quote(t *testing.T) {
 	}
 }
 
+func TestNewReader_OpenCloseQuote(t *testing.T) {
+
+	tests := []struct {
+		openQuote  rune
+		closeQuote rune
+		input      string
+		expected   [][]string
+	}{
+		{
+			openQuote:  '「',
+			closeQuote: '」',
+			input:      "「a,b」,「c」」d」,「x「y」\n「\n」,「」\n",
+			expected:   [][]string{{"a,b", "c」d", "x「y"}, {"\n", ""}},
+		},
+		{
+			openQuote:  '«',
+			closeQuote: '»',
+			input:      "«a»»»,\"b\"\n",
+			expected:   [][]string{{"a»", "\"b\""}},
+		},
+		{
+			openQuote:  '[',
+			closeQuote: ']',
+			input:      "[a]]b],[[c]\n",
+			expected:   [][]string{{"a]b", "[c"}},
+		},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.input))
+		r.OpenQuote = test.openQuote
+		r.CloseQuote = test.closeQuote
+		r.FieldsPerRecord = -1
+
+		records, err := r.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", string(test.openQuote), err)
+		}
+
+		if !reflect.DeepEqual(records, test.expected) {
+			t.Fatal("failed test\n", string(test.openQuote), records)
+		}
+	}
+}
+
+func TestNewReader_OpenCloseQuote_Error(t *testing.T) {
+
+	tests := []struct {
+		input  string
+		target error
+	}{
+		{input: "a「b」\n", target: ErrBareQuote},
+		{input: "a」b\n", target: ErrBareQuote},
+		{input: "「a」b\n", target: ErrUnescapedQuote},
+		{input: "「a」「b」\n", target: ErrUnescapedQuote},
+		{input: "「a\n", target: ErrQuoteNotClosed},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.input))
+		r.OpenQuote = '「'
+		r.CloseQuote = '」'
+
+		_, err := r.Read()
+		if !errors.Is(err, test.target) {
+			t.Fatal("failed test\n", test.input, err)
+		}
+	}
+}
+
+func TestNewReader_OpenCloseQuote_LazyQuotes(t *testing.T) {
+
+	s := "a」b,c「d,「e」f」,「g」 \n"
+
+	r := NewReader(strings.NewReader(s))
+	r.OpenQuote = '「'
+	r.CloseQuote = '」'
+	r.LazyQuotes = true
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"a」b", "c「d", "e」f", "g"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_Escape(t *testing.T) {
+
+	s := `a\,b,c\"d,"e\"f""g",h\\i,j\` + "\n" + `k\x` + "\n" +
+		`\"l,m,n\` + "\r" + `\` + "\n" + `o,p` + "\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.Escape = '\\'
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a,b", "c\"d", "e\"f\"g", "h\\i", "j\nkx"},
+		{"\"l", "m", "n\r\no", "p"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_Escape_EscapeSequences(t *testing.T) {
+
+	s := `a\nb\tc\rd\0e\xf\\n,"\n"` + "\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.Escape = '\\'
+	r.EscapeSequences = true
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"a\nb\tc\rd\x00exf\\n", "\n"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_Escape_SpecialRecordSeparator(t *testing.T) {
+
+	s := `a\||b,c\|||d\\||`
+
+	r := NewReader(strings.NewReader(s))
+	r.Escape = '\\'
+	r.SpecialRecordSeparator = "||"
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a||b", "c|"},
+		{"d\\"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_Escape_EOF(t *testing.T) {
+
+	r := NewReader(strings.NewReader(`a,b\`))
+	r.Escape = '\\'
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The Escape character at the end of the input is kept.
+	if !reflect.DeepEqual(record, []string{"a", "b\\"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_Escape_AfterQuotedField(t *testing.T) {
+
+	r := NewReader(strings.NewReader(`"a"\,b` + "\n"))
+	r.Escape = '\\'
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrUnescapedQuote) {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_QuoteNone(t *testing.T) {
+
+	s := `"a",b"c,"d` + "\n" + `"e` + "\n" + `f",""` + "\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.QuoteNone = true
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// Quote characters are ordinary characters.
+	expected := [][]string{
+		{`"a"`, `b"c`, `"d`},
+		{`"e`},
+		{`f"`, `""`},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
 func TestNewReader_FieldsPerRecord(t *testing.T) {
 
 	s := `a,b
@@ -447,7 +795,7 @@ c,d,e
 	{
 		// If the number of fields is different from the first record, an error will occur.
 		_, err := r.Read()
-		if err == nil || err.Error() != "parse error on record 2: wrong number of fields" {
+		if err == nil || err.Error() != "parse error on record 2: wrong number of fields (expected 2, actual 3)" {
 			t.Fatal("failed test\n", err)
 		}
 	}
@@ -476,7 +824,7 @@ c,d,e`
 	{
 		// If the number of fields is different from the first record, an error will occur.
 		_, err := r.Read()
-		if err == nil || err.Error() != "parse error on record 2: wrong number of fields" {
+		if err == nil || err.Error() != "parse error on record 2: wrong number of fields (expected 2, actual 3)" {
 			t.Fatal("failed test\n", err)
 		}
 	}
@@ -495,7 +843,7 @@ c,d
 	{
 		// The number of fields is different from the number specified in "FieldsPerRecord", so an error occurs.
 		_, err := r.Read()
-		if err == nil || err.Error() != "parse error on record 1: wrong number of fields" {
+		if err == nil || err.Error() != "parse error on record 1: wrong number of fields (expected 1, actual 2)" {
 			t.Fatal("failed test\n", err)
 		}
 	}
@@ -625,3 +973,1865 @@ func TestNewReader_OnlyLF(t *testing.T) {
 		t.Fatal("failed test\n", err)
 	}
 }
+
+func TestNewReader_HasHeader(t *testing.T) {
+
+	s := `id,name
+1,"a,b"
+2,c
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	header, err := r.Header()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(header, []string{"id", "name"}) {
+		t.Fatal("failed test\n", header)
+	}
+
+	// record:2
+	{
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"1", "a,b"}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	// record:3
+	{
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"2", "c"}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	_, err = r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_HasHeader_ReadAll(t *testing.T) {
+
+	s := `id,name
+1,a
+2,b
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	// The header is read implicitly and is not included in the records.
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"1", "a"},
+		{"2", "b"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+
+	header, err := r.Header()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(header, []string{"id", "name"}) {
+		t.Fatal("failed test\n", header)
+	}
+}
+
+func TestNewReader_ReadMap(t *testing.T) {
+
+	s := `id,name
+1,a
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	// record:2
+	{
+		m, err := r.ReadMap()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(m, map[string]string{"id": "1", "name": "a"}) {
+			t.Fatal("failed test\n", m)
+		}
+	}
+
+	_, err := r.ReadMap()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_ReadRecord(t *testing.T) {
+
+	s := `id,name
+1,a
+2
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	r.FieldsPerRecord = -1
+
+	// record:2
+	{
+		record, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if v, ok := record.Get("name"); !ok || v != "a" {
+			t.Fatal("failed test\n", v)
+		}
+
+		if _, ok := record.Get("xxx"); ok {
+			t.Fatal("failed test\n")
+		}
+	}
+
+	// record:3
+	{
+		record, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if v, ok := record.Get("id"); !ok || v != "2" {
+			t.Fatal("failed test\n", v)
+		}
+
+		// The column does not exist in the record.
+		if _, ok := record.Get("name"); ok {
+			t.Fatal("failed test\n")
+		}
+
+		if !reflect.DeepEqual(record.Map(), map[string]string{"id": "2"}) {
+			t.Fatal("failed test\n", record.Map())
+		}
+	}
+}
+
+func TestNewReader_HeaderIndex(t *testing.T) {
+
+	s := `id,name
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	i, err := r.HeaderIndex("name")
+	if err != nil || i != 1 {
+		t.Fatal("failed test\n", i, err)
+	}
+
+	i, err = r.HeaderIndex("xxx")
+	if err != nil || i != -1 {
+		t.Fatal("failed test\n", i, err)
+	}
+}
+
+func TestNewReader_HasHeader_FieldsPerRecord(t *testing.T) {
+
+	s := `id,name
+1,a,b
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	// The number of fields is checked against the header.
+	_, err := r.Read()
+	if err == nil || err.Error() != "parse error on record 2: wrong number of fields (expected 2, actual 3)" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_HasHeader_DuplicateName(t *testing.T) {
+
+	s := `id,name,id
+1,a,b
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	_, err := r.Read()
+	if err == nil || err.Error() != `parse error on record 1, column 3: duplicate header name "id"` {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_HasHeader_EmptyName(t *testing.T) {
+
+	s := `id,,name
+1,a,b
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	_, err := r.Header()
+	if err == nil || err.Error() != "parse error on record 1, column 2: empty header name" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_HasHeader_Empty(t *testing.T) {
+
+	s := ""
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	_, err := r.Header()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_NoHeader(t *testing.T) {
+
+	s := `id,name
+`
+
+	r := NewReader(strings.NewReader(s))
+
+	_, err := r.Header()
+	if err != ErrNoHeader {
+		t.Fatal("failed test\n", err)
+	}
+
+	_, err = r.ReadMap()
+	if err != ErrNoHeader {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_LazyQuotes_BareQuote(t *testing.T) {
+
+	s := `a,b"c",d"
+"e",f""
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+
+	// record:1
+	{
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"a", `b"c"`, `d"`}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	// record:2
+	{
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"e", `f""`}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	_, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_LazyQuotes_UnescapedQuote(t *testing.T) {
+
+	s := `a,"b"c",d
+"e"f,"g""h"
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+
+	// record:1
+	{
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"a", `b"c`, "d"}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	// record:2
+	{
+		// The quote is not closed, so the delimiter is a part of the field.
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{`e"f,"g"h`}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	_, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_LazyQuotes_SpaceAfterQuote(t *testing.T) {
+
+	s := "\"a\" ,\"b\"\t \n" +
+		"\"c\" d\",\"e\"  \"f\"\n" +
+		"\"g\"  "
+
+	r := NewReader(strings.NewReader(s))
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+
+	// record:1
+	{
+		// Whitespace between the closing quote and the delimiter/record separator is ignored.
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"a", "b"}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	// record:2
+	{
+		// Whitespace followed by other characters is a part of the field.
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{`c" d`, `e"  "f`}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	// record:3
+	{
+		// Whitespace before EOF is ignored.
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"g"}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	_, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_LazyQuotes_QuoteNotClose(t *testing.T) {
+
+	s := `"a`
+
+	r := NewReader(strings.NewReader(s))
+	r.LazyQuotes = true
+
+	_, err := r.Read()
+	if err == nil || err.Error() != "parse error on record 1, column 1: quote is not closed" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_SpaceAfterQuote(t *testing.T) {
+
+	s := `"a" ,b`
+
+	r := NewReader(strings.NewReader(s))
+
+	_, err := r.Read()
+	if err == nil || err.Error() != "parse error on record 1, column 1: unescaped quote in quoted field" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_Comment(t *testing.T) {
+
+	s := `# comment
+a,b
+#c,d
+"#e",f
+g,"h
+#i"
+ #j,k
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.Comment = '#'
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"#e", "f"},
+		{"g", "h\n#i"},
+		{" #j", "k"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_Comment_SpecialRecordSeparator(t *testing.T) {
+
+	s := `;comment
+x|a,b|;c,d|e,f`
+
+	r := NewReader(strings.NewReader(s))
+	r.Comment = ';'
+	r.SpecialRecordSeparator = "|"
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"e", "f"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_SkipLines(t *testing.T) {
+
+	s := `Report "2021
+Total: 2
+a,b
+c,d
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.SkipLines = 2
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"c", "d"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_SkipLines_EOF(t *testing.T) {
+
+	s := `a,b
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.SkipLines = 3
+
+	_, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_SkipUntil(t *testing.T) {
+
+	s := `Report
+date,2021-05-01
+id,name,price
+1,a,100
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	r.SkipUntil = func(record []string) bool {
+		return record[0] == "id"
+	}
+
+	header, err := r.Header()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(header, []string{"id", "name", "price"}) {
+		t.Fatal("failed test\n", header)
+	}
+
+	// The number of fields is checked against the header, not the skipped records.
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"1", "a", "100"}}) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_SkipUntil_NotFound(t *testing.T) {
+
+	s := `a,b
+c,d
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.SkipUntil = func(record []string) bool {
+		return false
+	}
+
+	_, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_FieldPos(t *testing.T) {
+
+	s := "a,\"b\nc\",d\r\n" +
+		"あ,\"e\r\nf\",g\n" +
+		"h"
+
+	r := NewReader(strings.NewReader(s))
+	r.FieldsPerRecord = -1
+
+	type pos struct {
+		line   int
+		column int
+	}
+
+	expected := [][]pos{
+		{{1, 1}, {1, 3}, {2, 4}},
+		{{3, 1}, {3, 5}, {4, 4}},
+		{{5, 1}},
+	}
+
+	for i, fieldPositions := range expected {
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		for j, expectedPos := range fieldPositions {
+			line, column := r.FieldPos(j)
+			if line != expectedPos.line || column != expectedPos.column {
+				t.Fatal("failed test\n", i, record, j, line, column)
+			}
+		}
+	}
+}
+
+func TestNewReader_FieldPos_OutOfRange(t *testing.T) {
+
+	r := NewReader(strings.NewReader("a,b"))
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("failed test\n")
+		}
+	}()
+
+	r.FieldPos(2)
+}
+
+func TestNewReader_InputOffset(t *testing.T) {
+
+	s := "\uFEFFa,あ\r\nb,c\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	if r.InputOffset() != 3 {
+		t.Fatal("failed test\n", r.InputOffset())
+	}
+
+	// record:1
+	{
+		if _, err := r.Read(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if r.InputOffset() != 10 {
+			t.Fatal("failed test\n", r.InputOffset())
+		}
+	}
+
+	// record:2
+	{
+		if _, err := r.Read(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if r.InputOffset() != 14 {
+			t.Fatal("failed test\n", r.InputOffset())
+		}
+	}
+}
+
+func TestNewReader_ParseErrorPosition(t *testing.T) {
+
+	s := "a,b\n" +
+		"\"c\n" +
+		"d\",e\"f\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	r.Read()
+	_, err := r.Read()
+
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatal("failed test\n", err)
+	}
+
+	if pe.Record != 2 || pe.Column != 2 || pe.StartLine != 2 || pe.Line != 3 || pe.ByteOffset != 11 {
+		t.Fatal("failed test\n", pe)
+	}
+}
+
+func TestNewReader_ParseErrorPosition_QuoteNotClose(t *testing.T) {
+
+	s := "a\n" +
+		"\"b\n" +
+		"c"
+
+	r := NewReader(strings.NewReader(s))
+
+	r.Read()
+	_, err := r.Read()
+
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatal("failed test\n", err)
+	}
+
+	if pe.Record != 2 || pe.Column != 1 || pe.StartLine != 2 || pe.Line != 3 || pe.ByteOffset != int64(len(s)) {
+		t.Fatal("failed test\n", pe)
+	}
+}
+
+func TestNewReader_ParseErrorPosition_FieldsPerRecord(t *testing.T) {
+
+	s := "# comment\n" +
+		"a,b\n" +
+		"c\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.Comment = '#'
+
+	r.Read()
+	_, err := r.Read()
+
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatal("failed test\n", err)
+	}
+
+	if pe.Record != 2 || pe.Column != 0 || pe.StartLine != 3 || pe.Line != 3 || pe.ByteOffset != 14 {
+		t.Fatal("failed test\n", pe)
+	}
+}
+
+func TestNewReader_ParseErrorPosition_Header(t *testing.T) {
+
+	s := "\"id\nx\",name,\"id\nx\"\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	_, err := r.Header()
+
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatal("failed test\n", err)
+	}
+
+	if pe.Record != 1 || pe.Column != 3 || pe.StartLine != 1 || pe.Line != 2 || pe.ByteOffset != 12 {
+		t.Fatal("failed test\n", pe)
+	}
+}
+
+func TestNewReader_ErrorsIs(t *testing.T) {
+
+	tests := []struct {
+		s      string
+		target error
+	}{
+		{"\"a", ErrQuoteNotClosed},
+		{"a\"", ErrBareQuote},
+		{"\"a\"b", ErrUnescapedQuote},
+		{"a\nb,c", ErrFieldCount},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.s))
+
+		_, err := r.ReadAll()
+		if !errors.Is(err, test.target) {
+			t.Fatal("failed test\n", test.s, err)
+		}
+
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatal("failed test\n", test.s, err)
+		}
+	}
+}
+
+func TestNewReader_ErrorsIs_Header(t *testing.T) {
+
+	tests := []struct {
+		s      string
+		target error
+	}{
+		{"a,,b", ErrEmptyHeader},
+		{"a,b,a", ErrDuplicateHeader},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.s))
+		r.HasHeader = true
+
+		_, err := r.Header()
+		if !errors.Is(err, test.target) {
+			t.Fatal("failed test\n", test.s, err)
+		}
+	}
+}
+
+func TestNewReader_HeaderError_Sticky(t *testing.T) {
+
+	r := NewReader(strings.NewReader("a,a\n1,2\n3,4\n"))
+	r.HasHeader = true
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrDuplicateHeader) {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The data records are not read as the header.
+	for i := 0; i < 2; i++ {
+		record, err := r.Read()
+		if !errors.Is(err, ErrDuplicateHeader) {
+			t.Fatal("failed test\n", record, err)
+		}
+	}
+
+	if _, err := r.ReadMap(); !errors.Is(err, ErrDuplicateHeader) {
+		t.Fatal("failed test\n", err)
+	}
+
+	header, err := r.Header()
+	if !errors.Is(err, ErrDuplicateHeader) || header != nil {
+		t.Fatal("failed test\n", header, err)
+	}
+
+	if _, err := r.HeaderIndex("a"); !errors.Is(err, ErrDuplicateHeader) {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_FieldCountError(t *testing.T) {
+
+	s := `a,b,c
+d
+`
+
+	r := NewReader(strings.NewReader(s))
+
+	_, err := r.ReadAll()
+
+	var fce *FieldCountError
+	if !errors.As(err, &fce) {
+		t.Fatal("failed test\n", err)
+	}
+
+	if fce.Expected != 3 || fce.Actual != 1 {
+		t.Fatal("failed test\n", fce)
+	}
+}
+
+func TestNewReader_ErrorHandler(t *testing.T) {
+
+	s := "a,b\r\n" +
+		"c,d\"\r\n" +
+		"e,f,g\n" +
+		"\"h\"i,j\n" +
+		"k,l\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	errs := []*ParseError{}
+	r.ErrorHandler = func(err *ParseError) error {
+		errs = append(errs, err)
+		return nil
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"k", "l"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+
+	if len(errs) != 3 {
+		t.Fatal("failed test\n", errs)
+	}
+
+	if !errors.Is(errs[0], ErrBareQuote) || errs[0].Record != 2 || errs[0].Raw != "c,d\"" {
+		t.Fatal("failed test\n", errs[0])
+	}
+	if !errors.Is(errs[1], ErrFieldCount) || errs[1].Record != 3 || errs[1].Raw != "e,f,g" {
+		t.Fatal("failed test\n", errs[1])
+	}
+	if !errors.Is(errs[2], ErrUnescapedQuote) || errs[2].Record != 4 || errs[2].Raw != "\"h\"i,j" {
+		t.Fatal("failed test\n", errs[2])
+	}
+}
+
+func TestNewReader_ErrorHandler_QuotedNewline(t *testing.T) {
+
+	s := "a,b\n" +
+		"x\"y,\"p\nq\"\n" +
+		"c,d\n" +
+		"\"e\"f,\"g\r\n\"\"h\"\n" +
+		"i,\"j\"\n" +
+		"k\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	errs := []*ParseError{}
+	r.ErrorHandler = func(err *ParseError) error {
+		errs = append(errs, err)
+		return nil
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The newlines in the quoted fields after the errors do not end the records.
+	expected := [][]string{
+		{"a", "b"},
+		{"c", "d"},
+		{"i", "j"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+
+	if len(errs) != 3 {
+		t.Fatal("failed test\n", errs)
+	}
+
+	if !errors.Is(errs[0], ErrBareQuote) || errs[0].Record != 2 || errs[0].Raw != "x\"y,\"p\nq\"" {
+		t.Fatal("failed test\n", errs[0])
+	}
+	if !errors.Is(errs[1], ErrUnescapedQuote) || errs[1].Record != 4 || errs[1].Raw != "\"e\"f,\"g\r\n\"\"h\"" {
+		t.Fatal("failed test\n", errs[1])
+	}
+	if !errors.Is(errs[2], ErrFieldCount) || errs[2].Record != 6 || errs[2].StartLine != 8 || errs[2].Raw != "k" {
+		t.Fatal("failed test\n", errs[2], errs[2].StartLine)
+	}
+}
+
+func TestNewReader_ErrorHandler_QuoteNotClose(t *testing.T) {
+
+	s := "a,b\n" +
+		"c,\"d\n" +
+		"e,f\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	errs := []*ParseError{}
+	r.ErrorHandler = func(err *ParseError) error {
+		errs = append(errs, err)
+		return nil
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"a", "b"}}) {
+		t.Fatal("failed test\n", records)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrQuoteNotClosed) || errs[0].Raw != "c,\"d\ne,f" {
+		t.Fatal("failed test\n", errs)
+	}
+}
+
+func TestNewReader_ErrorHandler_ReturnError(t *testing.T) {
+
+	s := "a,b\n" +
+		"c\n" +
+		"d,e\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	stop := errors.New("stop")
+	r.ErrorHandler = func(err *ParseError) error {
+		return stop
+	}
+
+	_, err := r.ReadAll()
+	if err != stop {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_MaxErrors(t *testing.T) {
+
+	s := "a,b\n" +
+		"c\n" +
+		"d\n" +
+		"e,f\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxErrors = 1
+
+	count := 0
+	r.ErrorHandler = func(err *ParseError) error {
+		count++
+		return nil
+	}
+
+	_, err := r.ReadAll()
+	if !errors.Is(err, ErrTooManyErrors) || !errors.Is(err, ErrFieldCount) {
+		t.Fatal("failed test\n", err)
+	}
+
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Record != 3 {
+		t.Fatal("failed test\n", err)
+	}
+
+	if count != 1 {
+		t.Fatal("failed test\n", count)
+	}
+}
+
+func TestNewReader_ReadAfterError(t *testing.T) {
+
+	s := "a,b\n" +
+		"c,\"d\"e,f\n" +
+		"g,h\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	r.Read()
+
+	// record:2
+	{
+		_, err := r.Read()
+		if err == nil || err.Error() != "parse error on record 2, column 2: unescaped quote in quoted field" {
+			t.Fatal("failed test\n", err)
+		}
+	}
+
+	// record:3
+	{
+		// Reading resumes from the next record.
+		record, err := r.Read()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, []string{"g", "h"}) {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	_, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_OneByteReader(t *testing.T) {
+
+	s := "a,\"b\r\n\"\"c\"\r\n" +
+		"日本語,\"d,e\"\r" +
+		"f,\"\"\n"
+
+	// Input is read one byte at a time, so that lookahead crosses the buffer boundary.
+	r := NewReader(iotest.OneByteReader(strings.NewReader(s)))
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b\r\n\"c"},
+		{"日本語", "d,e"},
+		{"f", ""},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_OneByteReader_SpecialRecordSeparator(t *testing.T) {
+
+	s := "a,b[RS]c,[RS[RS]d,\"[RS]\"[RS]"
+
+	r := NewReader(iotest.OneByteReader(strings.NewReader(s)))
+	r.SpecialRecordSeparator = "[RS]"
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"c", "[RS"},
+		{"d", "[RS]"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_NonAsciiFormat(t *testing.T) {
+
+	s := "あ、「い、う「、「え「「お「\n" +
+		"か、き、く\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.Delimiter = '、'
+	r.Quote = '「'
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"あ", "い、う", "え「お"},
+		{"か", "き", "く"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_InvalidUTF8(t *testing.T) {
+
+	s := "a\xff\xfeb,c\n"
+
+	r := NewReader(strings.NewReader(s))
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// Each invalid byte is replaced with U+FFFD.
+	if !reflect.DeepEqual(record, []string{"a\uFFFD\uFFFDb", "c"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_InvalidUTF8_Error(t *testing.T) {
+
+	s := "a,b\nc,\"d\"\"\xe3\x81\xff\"\ne,\uFFFD\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.InvalidUTF8 = InvalidUTF8Error
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrInvalidUTF8) {
+		t.Fatal("failed test\n", err)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Record != 2 || pe.Column != 2 || pe.StartLine != 2 || pe.Line != 2 || pe.ByteOffset != 10 {
+		t.Fatal("failed test\n", pe, pe.StartLine, pe.Line, pe.ByteOffset)
+	}
+
+	if err.Error() != "parse error on record 2, column 2: invalid UTF-8" {
+		t.Fatal("failed test\n", err)
+	}
+
+	// A literal U+FFFD is valid.
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"e", "\uFFFD"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_InvalidUTF8_Error_Multiline(t *testing.T) {
+
+	s := "\"a\nb\",\"c\r\n\xc0\"\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.InvalidUTF8 = InvalidUTF8Error
+
+	_, err := r.Read()
+
+	pe := &ParseError{}
+	if !errors.As(err, &pe) {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !errors.Is(err, ErrInvalidUTF8) || pe.Column != 2 || pe.StartLine != 1 || pe.Line != 3 || pe.ByteOffset != 10 {
+		t.Fatal("failed test\n", pe, pe.StartLine, pe.Line, pe.ByteOffset)
+	}
+}
+
+func TestNewReader_InvalidUTF8_Keep(t *testing.T) {
+
+	s := "a\xff\xfeb,c\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.InvalidUTF8 = InvalidUTF8Keep
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"a\xff\xfeb", "c"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_ControlChars_Error(t *testing.T) {
+
+	s := "a\tb,c\r\nd,\"e\r\nf\",g\x00h\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.ControlChars = ControlCharError
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// Tab, CR and LF are allowed.
+	if !reflect.DeepEqual(record, []string{"a\tb", "c"}) {
+		t.Fatal("failed test\n", record)
+	}
+
+	_, err = r.Read()
+	if !errors.Is(err, ErrControlChar) {
+		t.Fatal("failed test\n", err)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Record != 2 || pe.Column != 3 || pe.StartLine != 2 || pe.Line != 3 || pe.ByteOffset != 17 {
+		t.Fatal("failed test\n", pe, pe.StartLine, pe.Line, pe.ByteOffset)
+	}
+}
+
+func TestNewReader_ControlChars_Error_ErrorHandler(t *testing.T) {
+
+	s := "a,b\nc,\x1b[0m\ne,f\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.ControlChars = ControlCharError
+
+	raws := []string{}
+	r.ErrorHandler = func(err *ParseError) error {
+		raws = append(raws, err.Raw)
+		return nil
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"a", "b"}, {"e", "f"}}) {
+		t.Fatal("failed test\n", records)
+	}
+
+	if !reflect.DeepEqual(raws, []string{"c,\x1b[0m"}) {
+		t.Fatal("failed test\n", raws)
+	}
+}
+
+func TestNewReader_ControlChars_Strip(t *testing.T) {
+
+	s := "\x00a\x01,,\"\x02\",b\x1fc\x7f\r\nd\x00\x00e\\0,\x1b\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.ControlChars = ControlCharStrip
+	r.FieldsPerRecord = -1
+	r.Escape = '\\'
+	r.EscapeSequences = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "", "", "bc\x7f"},
+		{"de", ""},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_ControlChars_Delimiter(t *testing.T) {
+
+	// The control characters used as format characters are not in the fields.
+	s := "a\x1fb\x1e\"c\x1f\"\x1fd\x1e"
+
+	r := NewReader(strings.NewReader(s))
+	r.Delimiter = '\x1f'
+	r.SpecialRecordSeparator = "\x1e"
+	r.ControlChars = ControlCharStrip
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"a", "b"}, {"c", "d"}}) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_MaxFieldSize(t *testing.T) {
+
+	s := "abc,de\n\"abcd\",e\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxFieldSize = 3
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	le := &LimitError{}
+	if !errors.As(err, &le) || le.Limit != "MaxFieldSize" || le.Max != 3 {
+		t.Fatal("failed test\n", le)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Record != 2 || pe.Column != 1 || pe.Line != 2 || pe.ByteOffset != 12 {
+		t.Fatal("failed test\n", pe, pe.Line, pe.ByteOffset)
+	}
+
+	if err.Error() != "parse error on record 2, column 1: limit exceeded (MaxFieldSize 3)" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_MaxFieldSize_QuoteNotClosed(t *testing.T) {
+
+	// The rest of the input is not read into the field.
+	s := "a,\"b" + strings.Repeat("c\n", 1<<20)
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxFieldSize = 1024
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Column != 2 || pe.ByteOffset > 1024+4096 {
+		t.Fatal("failed test\n", pe, pe.ByteOffset)
+	}
+}
+
+func TestNewReader_MaxRecordSize(t *testing.T) {
+
+	s := "ab,cd\nab,cd,e\nf\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxRecordSize = 4
+	r.FieldsPerRecord = -1
+	r.ErrorHandler = func(err *ParseError) error {
+		t.Fatal("failed test\n", err)
+		return nil
+	}
+
+	records := [][]string{}
+	errs := []error{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// The error of a limit is returned without ErrorHandler.
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	// The next Read skips the rest of the record exceeding the limit.
+	if !reflect.DeepEqual(records, [][]string{{"ab", "cd"}, {"f"}}) {
+		t.Fatal("failed test\n", records)
+	}
+
+	if len(errs) != 1 || errs[0].Error() != "parse error on record 2, column 3: limit exceeded (MaxRecordSize 4)" {
+		t.Fatal("failed test\n", errs)
+	}
+}
+
+func TestNewReader_MaxFieldSize_Huge(t *testing.T) {
+
+	// An unclosed quote followed by a huge input.
+	size := 32 << 20
+	s := "a,\"b" + strings.Repeat("x", size) + "\nc,d\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxFieldSize = 1024
+
+	var raw string
+	r.ErrorHandler = func(err *ParseError) error {
+		raw = err.Raw
+		return nil
+	}
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	// Reading stops at the limit.
+	if r.InputOffset() > 64<<10 || len(raw) != 0 || len(r.raw) > 64<<10 {
+		t.Fatal("failed test\n", r.InputOffset(), len(raw), len(r.raw))
+	}
+
+	// The next Read skips the rest of the record without keeping it.
+	// The quote is not closed, so the rest of the input is in the record.
+	record, err := r.Read()
+	if err != io.EOF {
+		t.Fatal("failed test\n", record, err)
+	}
+
+	if cap(r.raw) > 64<<10 {
+		t.Fatal("failed test\n", cap(r.raw))
+	}
+}
+
+func TestNewReader_MaxFieldSize_QuotedNewline(t *testing.T) {
+
+	s := "\"aaaaaaaaaaaaaaaa\nb,c\"\nd,e\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxFieldSize = 5
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The newline in the quoted field does not end the record.
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"d", "e"}) {
+		t.Fatal("failed test\n", record)
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_MaxFieldsPerRecord(t *testing.T) {
+
+	s := "a,b,c\n\"d,e\",f,g,h\ni,j\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxFieldsPerRecord = 3
+	r.FieldsPerRecord = -1
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Record != 2 || pe.Column != 4 || pe.ByteOffset != 15 {
+		t.Fatal("failed test\n", pe, pe.ByteOffset)
+	}
+
+	// Reading continues from the next record.
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"i", "j"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_MaxRecords(t *testing.T) {
+
+	s := "h1,h2\na,b\nc,d\ne,f\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+	r.MaxRecords = 3
+	r.ErrorHandler = func(err *ParseError) error {
+		t.Fatal("failed test\n", err)
+		return nil
+	}
+
+	_, err := r.ReadAll()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	pe := err.(*ParseError)
+	if pe.Record != 4 || pe.Column != 0 || pe.StartLine != 4 {
+		t.Fatal("failed test\n", pe, pe.StartLine)
+	}
+
+	if err.Error() != "parse error on record 4: limit exceeded (MaxRecords 3)" {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_MaxRecords_NotParsed(t *testing.T) {
+
+	// The records after the limit are not parsed.
+	size := 32 << 20
+	s := "a,b\n\"c\nd" + strings.Repeat("x", size) + "\"\ne,f\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxRecords = 1
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	_, err := r.Read()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("failed test\n", err)
+	}
+
+	if pe := err.(*ParseError); pe.Record != 2 || pe.StartLine != 2 || r.InputOffset() != 4 {
+		t.Fatal("failed test\n", pe, pe.StartLine, r.InputOffset())
+	}
+
+	// Each of the following records is skipped with the error.
+	_, err = r.Read()
+	if pe, ok := err.(*ParseError); !ok || !errors.Is(err, ErrLimitExceeded) || pe.Record != 3 || pe.StartLine != 4 {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The huge record is skipped without keeping it.
+	if r.InputOffset() != int64(10+size) || cap(r.recordBuffer) > 64<<10 {
+		t.Fatal("failed test\n", r.InputOffset(), cap(r.recordBuffer))
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestNewReader_MaxRecords_NotExceeded(t *testing.T) {
+
+	s := "a,b\nc,d\n"
+
+	r := NewReader(strings.NewReader(s))
+	r.MaxRecords = 2
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"a", "b"}, {"c", "d"}}) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestReader_Validate(t *testing.T) {
+
+	tests := []struct {
+		setup   func(r *Reader)
+		message string
+	}{
+		{
+			setup:   func(r *Reader) { r.Quote = ',' },
+			message: `invalid dialect: delimiter "," conflicts with quote ","`,
+		},
+		{
+			setup: func(r *Reader) {
+				r.DelimiterString = "|"
+				r.SpecialRecordSeparator = "|"
+			},
+			message: `invalid dialect: delimiter "|" conflicts with record separator "|"`,
+		},
+		{
+			setup:   func(r *Reader) { r.SpecialRecordSeparator = "\"|" },
+			message: `invalid dialect: quote "\"" conflicts with record separator "\"|"`,
+		},
+		{
+			setup: func(r *Reader) {
+				r.Delimiter = utf8.RuneError
+				r.Comment = '"'
+			},
+			message: `invalid dialect: delimiter '�' is not a valid character; quote "\"" conflicts with comment "\""`,
+		},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader("a,b\n"))
+		test.setup(r)
+
+		err := r.Validate()
+		if !errors.Is(err, ErrInvalidDialect) || err.Error() != test.message {
+			t.Fatal("failed test\n", err)
+		}
+
+		// It is also checked by Read.
+		_, err = r.Read()
+		if !errors.Is(err, ErrInvalidDialect) || err.Error() != test.message {
+			t.Fatal("failed test\n", err)
+		}
+	}
+}
+
+func TestReader_Validate_Changed(t *testing.T) {
+
+	r := NewReader(strings.NewReader("a,b\nc;d\n"))
+
+	if err := r.Validate(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The format characters changed after the first Read are also checked.
+	r.Delimiter = '"'
+	if _, err := r.Read(); !errors.Is(err, ErrInvalidDialect) {
+		t.Fatal("failed test\n", err)
+	}
+
+	r.Delimiter = ';'
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"c", "d"}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
+func TestNewReader_ReuseRecord(t *testing.T) {
+
+	s := `a,b
+c,d
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.ReuseRecord = true
+
+	record1, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record1, []string{"a", "b"}) {
+		t.Fatal("failed test\n", record1)
+	}
+
+	record2, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record2, []string{"c", "d"}) {
+		t.Fatal("failed test\n", record2)
+	}
+
+	// The backing array is shared.
+	if &record1[0] != &record2[0] {
+		t.Fatal("failed test\n", record1)
+	}
+}
+
+func TestNewReader_ReuseRecord_ReadAll(t *testing.T) {
+
+	s := `a,b
+c,d
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.ReuseRecord = true
+
+	// ReadAll does not reuse records.
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := [][]string{
+		{"a", "b"},
+		{"c", "d"},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewReader_ReadBytes(t *testing.T) {
+
+	s := `id,name
+1,"a,""b"""
+2,
+`
+
+	r := NewReader(strings.NewReader(s))
+	r.HasHeader = true
+
+	// record:2
+	{
+		record, err := r.ReadBytes()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(record, [][]byte{[]byte("1"), []byte(`a,"b"`)}) {
+			t.Fatal("failed test\n", record)
+		}
+
+		// Appending to a field does not overwrite the next field.
+		_ = append(record[0], 'x')
+		if string(record[1]) != `a,"b"` {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	// record:3
+	{
+		record, err := r.ReadBytes()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if len(record) != 2 || string(record[0]) != "2" || len(record[1]) != 0 {
+			t.Fatal("failed test\n", record)
+		}
+	}
+
+	_, err := r.ReadBytes()
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+
+	header, err := r.Header()
+	if err != nil || !reflect.DeepEqual(header, []string{"id", "name"}) {
+		t.Fatal("failed test\n", header, err)
+	}
+}
+
+func TestNewReader_ReadBytes_Allocs(t *testing.T) {
+
+	s := strings.Repeat("abc,\"d,e\",fgh\r\n", 1000)
+	r := NewReader(strings.NewReader(s))
+
+	// Warm up the internal buffers.
+	if _, err := r.ReadBytes(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := r.ReadBytes(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+	})
+
+	if allocs != 0 {
+		t.Fatal("failed test\n", allocs)
+	}
+}
+
+func benchmarkInput() string {
+
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		b.WriteString("12345,abcdefghij,\"quoted, field\",\"escaped \"\"quote\"\"\",日本語テキスト,\"multi\r\nline\"\r\n")
+	}
+	return b.String()
+}
+
+func BenchmarkReader_Read(b *testing.B) {
+
+	s := benchmarkInput()
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(s))
+		for {
+			_, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkReader_Read_EncodingCSV is the baseline of BenchmarkReader_Read with encoding/csv.
+func BenchmarkReader_Read_EncodingCSV(b *testing.B) {
+
+	s := benchmarkInput()
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		r := csv.NewReader(strings.NewReader(s))
+		for {
+			_, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkReader_Read_ReuseRecord(b *testing.B) {
+
+	s := benchmarkInput()
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(s))
+		r.ReuseRecord = true
+		for {
+			_, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkReader_ReadBytes(b *testing.B) {
+
+	s := benchmarkInput()
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(s))
+		for {
+			_, err := r.ReadBytes()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package customcsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"slices"
+)
+
+// Confidence is the likelihood of a sniffed Dialect, from 0 to 1.
+// It is the ratio of the sampled records that have the same number of fields,
+// and 0 if no delimiter is found.
+type Confidence float64
+
+// sniffSize is the size of the sample inspected by Sniff.
+const sniffSize = 64 << 10
+
+var (
+	// sniffDelimiters are the candidates of the delimiter in order of priority.
+	sniffDelimiters = []rune{',', ';', '\t', '|', ':', '\x1f'}
+
+	// sniffQuotes are the candidates of the quote in order of priority.
+	sniffQuotes = []rune{'"', '\''}
+)
+
+// Sniff infers the Dialect from a sample at the beginning of r.
+// It detects the delimiter, the quote, the record separator, the BOM,
+// and whether the first record is a header.
+// If r is empty, it returns io.EOF.
+func Sniff(r io.Reader) (Dialect, Confidence, error) {
+
+	sample, err := io.ReadAll(io.LimitReader(r, sniffSize))
+	if err != nil {
+		return Dialect{}, 0, err
+	}
+
+	if len(sample) == 0 {
+		return Dialect{}, 0, io.EOF
+	}
+
+	d, confidence := sniff(sample, len(sample) < sniffSize)
+	return d, confidence, nil
+}
+
+// NewReaderAuto returns a new Reader that reads from r in the Dialect inferred by Sniff.
+// The sample inspected by Sniff is also read by the Reader.
+// If the first record is inferred as a header, HasHeader of the Reader is true.
+func NewReaderAuto(r io.Reader) (*Reader, Dialect, error) {
+
+	br := bufio.NewReaderSize(r, sniffSize)
+
+	sample, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return nil, Dialect{}, err
+	}
+
+	d := DialectRFC4180()
+	if len(sample) != 0 {
+		d, _ = sniff(sample, err == io.EOF)
+	}
+
+	return NewReaderDialect(br, d), d, nil
+}
+
+// sniff infers the Dialect from the sample. eof indicates that the sample is the whole input.
+func sniff(sample []byte, eof bool) (Dialect, Confidence) {
+
+	d := DialectRFC4180()
+
+	if bytes.HasPrefix(sample, utf8bom) {
+		d.BOM = true
+		sample = sample[len(utf8bom):]
+	}
+
+	d.RecordSeparator = sniffRecordSeparator(sample)
+	d.Quote = sniffQuote(sample)
+
+	var best sniffResult
+	for _, delimiter := range sniffDelimiters {
+		candidate := d
+		candidate.Delimiter = delimiter
+
+		result := sniffDialect(sample, eof, candidate)
+		if result.better(best) {
+			best = result
+		}
+	}
+
+	if best.records == nil {
+		// No delimiter is found.
+		return d, 0
+	}
+
+	d = best.dialect
+	d.HasHeader = sniffHeader(best.records, best.fields)
+	return d, Confidence(best.consistency)
+}
+
+// sniffRecordSeparator returns the most frequent record separator in the sample.
+func sniffRecordSeparator(sample []byte) string {
+
+	crlf := bytes.Count(sample, []byte("\r\n"))
+	counts := []struct {
+		separator string
+		count     int
+	}{
+		{"\r\n", crlf},
+		{"\n", bytes.Count(sample, []byte("\n")) - crlf},
+		{"\r", bytes.Count(sample, []byte("\r")) - crlf},
+		{"\x1e", bytes.Count(sample, []byte("\x1e"))},
+	}
+
+	separator := "\r\n"
+	most := 0
+	for _, c := range counts {
+		if c.count > most {
+			separator = c.separator
+			most = c.count
+		}
+	}
+
+	return separator
+}
+
+// sniffQuote returns the quote that appears most at the beginning or the end of fields.
+func sniffQuote(sample []byte) rune {
+
+	boundary := func(b byte) bool {
+		return b == '\r' || b == '\n' || b == '\x1e' || slices.Contains(sniffDelimiters, rune(b))
+	}
+
+	quote := sniffQuotes[0]
+	most := 0
+	for _, q := range sniffQuotes {
+		count := 0
+		for i, b := range sample {
+			if rune(b) != q {
+				continue
+			}
+			if i == 0 || boundary(sample[i-1]) || i == len(sample)-1 || boundary(sample[i+1]) {
+				count++
+			}
+		}
+
+		if count > most {
+			quote = q
+			most = count
+		}
+	}
+
+	return quote
+}
+
+// sniffResult is the result of parsing the sample in a candidate Dialect.
+type sniffResult struct {
+	dialect Dialect
+
+	// records are the parsed records, except for empty lines. nil if the candidate is not valid.
+	records [][]string
+
+	// fields is the most frequent number of fields.
+	fields int
+
+	// consistency is the ratio of the records that have the most frequent number of fields.
+	consistency float64
+}
+
+// better reports whether the result is better than the other.
+func (s sniffResult) better(other sniffResult) bool {
+
+	if s.records == nil {
+		return false
+	}
+	if other.records == nil {
+		return true
+	}
+
+	if s.consistency != other.consistency {
+		return s.consistency > other.consistency
+	}
+	return s.fields > other.fields
+}
+
+// sniffDialect parses the sample in the candidate Dialect.
+func sniffDialect(sample []byte, eof bool, d Dialect) sniffResult {
+
+	r := NewReaderDialect(bytes.NewReader(sample), d)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records := [][]string{}
+	for {
+		record, err := r.Read()
+		if err != nil {
+			// A parse error is at the end of the sample, if it is truncated.
+			break
+		}
+		if len(record) == 1 && record[0] == "" {
+			// Empty line.
+			continue
+		}
+		records = append(records, record)
+	}
+
+	if !eof && len(records) > 1 {
+		// The last record may be truncated.
+		records = records[:len(records)-1]
+	}
+
+	counts := map[int]int{}
+	fields := 0
+	for _, record := range records {
+		counts[len(record)]++
+		if counts[len(record)] > counts[fields] || (counts[len(record)] == counts[fields] && len(record) > fields) {
+			fields = len(record)
+		}
+	}
+
+	if fields < 2 {
+		// The delimiter is not used.
+		return sniffResult{}
+	}
+
+	return sniffResult{
+		dialect:     d,
+		records:     records,
+		fields:      fields,
+		consistency: float64(counts[fields]) / float64(len(records)),
+	}
+}
+
+// sniffHeader reports whether the first record looks like a header.
+// Each column votes for a header, if the first value differs from the other values in type or length.
+func sniffHeader(records [][]string, fields int) bool {
+
+	if len(records) < 2 || len(records[0]) != fields {
+		return false
+	}
+
+	header := records[0]
+	for i, name := range header {
+		if name == "" || slices.Contains(header[:i], name) {
+			return false
+		}
+	}
+
+	votes := 0
+	for column, name := range header {
+		values := []string{}
+		for _, record := range records[1:] {
+			if len(record) == fields {
+				values = append(values, record[column])
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		numeric := true
+		length := len(values[0])
+		for _, value := range values {
+			if !isNumeric(value) {
+				numeric = false
+			}
+			if len(value) != length {
+				length = -1
+			}
+		}
+
+		switch {
+		case numeric:
+			if isNumeric(name) {
+				votes--
+			} else {
+				votes++
+			}
+		case length >= 0:
+			if len(name) != length {
+				votes++
+			} else {
+				votes--
+			}
+		}
+	}
+
+	return votes > 0
+}
@@ -0,0 +1,174 @@
+package customcsv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		input      string
+		expected   Dialect
+		confidence Confidence
+	}{
+		{
+			name:       "comma",
+			input:      "id,name,price\r\n1,apple,100\r\n2,\"banana, yellow\",250\r\n",
+			expected:   Dialect{Delimiter: ',', Quote: '"', RecordSeparator: "\r\n", HasHeader: true},
+			confidence: 1,
+		},
+		{
+			name:       "semicolon",
+			input:      "a;b,c;d\ne;f,g;h\ni;\"j;k\";l\n",
+			expected:   Dialect{Delimiter: ';', Quote: '"', RecordSeparator: "\n"},
+			confidence: 1,
+		},
+		{
+			name:       "tab",
+			input:      "x\ty\tz\r1\t2\t3\r4\t5\r",
+			expected:   Dialect{Delimiter: '\t', Quote: '"', RecordSeparator: "\r", HasHeader: true},
+			confidence: 2.0 / 3,
+		},
+		{
+			name:       "pipe",
+			input:      "code|value\nA01|x\nA02|y\n",
+			expected:   Dialect{Delimiter: '|', Quote: '"', RecordSeparator: "\n", HasHeader: true},
+			confidence: 1,
+		},
+		{
+			name:       "single quote",
+			input:      "'a,b',c\n'd,e',f\n'g','h,i'\n",
+			expected:   Dialect{Delimiter: ',', Quote: '\'', RecordSeparator: "\n"},
+			confidence: 1,
+		},
+		{
+			name:       "unit separator",
+			input:      "a\x1fb\x1ec\x1fd\x1e",
+			expected:   Dialect{Delimiter: '\x1f', Quote: '"', RecordSeparator: "\x1e"},
+			confidence: 1,
+		},
+		{
+			name:       "BOM",
+			input:      "\uFEFFa,b\r\nc,d\r\n",
+			expected:   Dialect{Delimiter: ',', Quote: '"', RecordSeparator: "\r\n", BOM: true},
+			confidence: 1,
+		},
+		{
+			name:       "no delimiter",
+			input:      "a\nb\nc\n",
+			expected:   Dialect{Delimiter: ',', Quote: '"', RecordSeparator: "\n"},
+			confidence: 0,
+		},
+	}
+
+	for _, test := range tests {
+		d, confidence, err := Sniff(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatal("failed test\n", test.name, err)
+		}
+
+		if !reflect.DeepEqual(d, test.expected) {
+			t.Fatal("failed test\n", test.name, d)
+		}
+
+		if confidence != test.confidence {
+			t.Fatal("failed test\n", test.name, confidence)
+		}
+	}
+}
+
+func TestSniff_Empty(t *testing.T) {
+
+	_, _, err := Sniff(strings.NewReader(""))
+	if err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
+
+func TestSniffHeader(t *testing.T) {
+
+	tests := []struct {
+		records  [][]string
+		expected bool
+	}{
+		// Numbers under a name.
+		{[][]string{{"id", "value"}, {"1", "2"}, {"3", "4"}}, true},
+		// Numbers only.
+		{[][]string{{"1", "2"}, {"3", "4"}}, false},
+		// Fixed length values under a name of another length.
+		{[][]string{{"code", "flag"}, {"A1", "y"}, {"B2", "z"}}, true},
+		// Values of various lengths.
+		{[][]string{{"a", "bb"}, {"ccc", "d"}, {"e", "ffff"}}, false},
+		// Empty name.
+		{[][]string{{"", "value"}, {"1", "2"}}, false},
+		// Duplicate names.
+		{[][]string{{"id", "id"}, {"1", "2"}}, false},
+		// Only one record.
+		{[][]string{{"id", "value"}}, false},
+	}
+
+	for _, test := range tests {
+		if sniffHeader(test.records, 2) != test.expected {
+			t.Fatal("failed test\n", test.records)
+		}
+	}
+}
+
+func TestNewReaderAuto(t *testing.T) {
+
+	var b strings.Builder
+	b.WriteString("id;name\n")
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&b, "%d;\"name\n%d\"\n", i, i)
+	}
+	s := b.String()
+
+	r, d, err := NewReaderAuto(strings.NewReader(s))
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	expected := Dialect{Delimiter: ';', Quote: '"', RecordSeparator: "\n", HasHeader: true}
+	if !reflect.DeepEqual(d, expected) {
+		t.Fatal("failed test\n", d)
+	}
+
+	header, err := r.Header()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if !reflect.DeepEqual(header, []string{"id", "name"}) {
+		t.Fatal("failed test\n", header)
+	}
+
+	// The sniffed bytes are also read.
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if len(records) != 10000 || !reflect.DeepEqual(records[9999], []string{"9999", "name\n9999"}) {
+		t.Fatal("failed test\n", len(records), records[len(records)-1])
+	}
+}
+
+func TestNewReaderAuto_Empty(t *testing.T) {
+
+	r, d, err := NewReaderAuto(strings.NewReader(""))
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(d, DialectRFC4180()) {
+		t.Fatal("failed test\n", d)
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatal("failed test\n", err)
+	}
+}
@@ -0,0 +1,45 @@
+#!/usr/bin/env python3
+"""Generates tables.go, the mapping tables of the Japanese encodings.
+
+The tables are derived from the codecs of Python:
+  jis0208Table: Windows-31J (cp932), indexed by pointer = (lead - offset) * 188 + (trail - offset).
+                The pointers less than 94 * 94 are shared by EUC-JP as (row * 94 + cell).
+  jis0212Table: JIS X 0212 of EUC-JP (euc_jp, 0x8F prefixed), indexed by row * 94 + cell.
+
+Usage: python3 tools/gentables.py > tables.go
+"""
+
+
+def cp932(pointer):
+    lead, trail = divmod(pointer, 188)
+    lead += 0x81 if lead < 0x1F else 0xC1
+    trail += 0x40 if trail < 0x3F else 0x41
+    try:
+        s = bytes([lead, trail]).decode("cp932")
+    except UnicodeDecodeError:
+        return 0
+    return ord(s) if len(s) == 1 else 0
+
+
+def jis0212(pointer):
+    row, cell = divmod(pointer, 94)
+    try:
+        s = bytes([0x8F, row + 0xA1, cell + 0xA1]).decode("euc_jp")
+    except UnicodeDecodeError:
+        return 0
+    return ord(s) if len(s) == 1 else 0
+
+
+def table(name, comment, values):
+    lines = ["// %s" % comment, "var %s = [...]uint16{" % name]
+    for i in range(0, len(values), 12):
+        lines.append("\t" + " ".join("0x%04X," % v for v in values[i:i + 12]))
+    lines.append("}")
+    return "\r\n".join(lines)
+
+
+print("// Code generated by tools/gentables.py. DO NOT EDIT.\r\n\r\npackage customcsv\r\n\r\n" +
+      table("jis0208Table", "jis0208Table maps the pointers of Windows-31J to runes. 0 if not mapped.",
+            [cp932(p) for p in range(60 * 188)]) + "\r\n\r\n" +
+      table("jis0212Table", "jis0212Table maps the pointers of JIS X 0212 to runes. 0 if not mapped.",
+            [jis0212(p) for p in range(94 * 94)]), end="\r\n")
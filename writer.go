@@ -2,8 +2,12 @@ package customcsv
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 type Writer struct {
@@ -11,47 +15,200 @@ type Writer struct {
 	// It is set to default comma (',') by NewWriter.
 	Delimiter rune
 
+	// DelimiterString is the field delimiter of multiple characters, such as "||".
+	// If specified, it is used instead of Delimiter.
+	DelimiterString string
+
 	// Quote is the field quote character.
 	// It is set to default double quote ('"') by NewWriter.
 	Quote rune
 
-	// If True, always quote the fields.
+	// OpenQuote and CloseQuote are the quote characters at the beginning and the end of a field,
+	// such as '「' and '」'. If 0, Quote is used. (default)
+	// A CloseQuote in a quoted field is escaped by doubling it.
+	OpenQuote  rune
+	CloseQuote rune
+
+	// If True, always quote the fields. It is the same as QuotePolicy QuoteAll.
 	AllQuotes bool
 
+	// QuotePolicy is the policy to quote the fields.
+	// It is set to default QuoteMinimal by NewWriter.
+	QuotePolicy QuotePolicy
+
+	// ColumnQuotePolicy overrides QuotePolicy for the columns specified by index. (0-based)
+	ColumnQuotePolicy map[int]QuotePolicy
+
+	// HeaderQuotePolicy overrides QuotePolicy for the columns specified by header name.
+	// The header names are taken from the first record written.
+	// ColumnQuotePolicy takes precedence over it.
+	HeaderQuotePolicy map[string]QuotePolicy
+
+	// QuoteSpaces indicates that the fields with leading or trailing spaces are quoted.
+	QuoteSpaces bool
+
+	// Escape is the escape character, such as '\\'.
+	// If specified, the characters that would require quoting are written following the Escape character,
+	// and the fields are not quoted unless AllQuotes is true.
+	// If 0, escapes are not used. (default)
+	Escape rune
+
+	// EscapeSequences indicates that a newline, a tab, a carriage return and a NUL
+	// are written as "\n", "\t", "\r" and "\0" following the Escape character.
+	EscapeSequences bool
+
+	// QuoteNone disables quoting. If true, the fields are never quoted.
+	// A field that contains the delimiter, the record separator or a newline, or the first field
+	// beginning with the Comment character, is written with the Escape character if specified,
+	// otherwise Write returns a WriteError wrapping ErrQuoteRequired.
+	QuoteNone bool
+
 	// RecordSeparator is the record separator.
 	// It is set to default CRLF ('\r\n') by NewWriter.
 	RecordSeparator string
 
-	w *bufio.Writer
+	// Comment is the comment character written by WriteComment.
+	// The first field beginning with it is quoted or escaped, so as not to be read as a comment line.
+	// It is set to default '#' by NewWriter.
+	Comment rune
+
+	// BOM indicates that the BOM is written at the beginning of the output.
+	// It is the UTF-8 BOM, or the BOM in the Encoding, such as UTF16LE.
+	// If the Encoding has no BOM, such as Windows31J, it is not written.
+	BOM bool
+
+	// Encoding is the character encoding of the output, such as Windows31J.
+	// If nil, the output is UTF-8. (default)
+	Encoding Encoding
+
+	// Replacement is the character written instead of the characters
+	// that cannot be represented in the Encoding, such as '?'.
+	// If 0, Write and WriteComment return a WriteError wrapping ErrUnrepresentable. (default)
+	// The format characters and Replacement itself must be representable, which is checked by Validate.
+	Replacement rune
+
+	// out is the output in the Encoding, and w is the output in UTF-8 written to out.
+	// They are the same if the Encoding is not used.
+	out       *bufio.Writer
+	w         *bufio.Writer
+	started   bool
+	numRecord int
+	header    []string
+}
+
+// QuotePolicy is the policy to quote fields in Writer.
+type QuotePolicy int
+
+const (
+	// QuoteMinimal quotes only the fields that contain format characters or newlines.
+	QuoteMinimal QuotePolicy = iota
+
+	// QuoteAll quotes all the fields.
+	QuoteAll
+
+	// QuoteNonNumeric quotes the fields that are not numbers, in addition to QuoteMinimal.
+	QuoteNonNumeric
+
+	// QuoteNonEmpty quotes the fields that are not empty.
+	QuoteNonEmpty
+)
+
+// WriteError is the error that a record or a comment cannot be written.
+type WriteError struct {
+	// Record is the record number where the error occurred. (1-based)
+	// For a comment, it is the number of the records written before the comment.
+	Record int
+
+	// Column is the field number where the error occurred. (1-based, 0 for a comment)
+	Column int
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *WriteError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("write error on comment after record %d: %v", e.Record, e.Err)
+	}
+	return fmt.Sprintf("write error on record %d, column %d: %v", e.Record, e.Column, e.Err)
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
 }
 
+var (
+	// ErrQuoteRequired is the error that a field cannot be written without quotes, since QuoteNone is specified.
+	ErrQuoteRequired = errors.New("field requires quotes")
+
+	// ErrUnrepresentable is the error that a character cannot be represented in the Encoding.
+	ErrUnrepresentable = errors.New("character cannot be represented in the encoding")
+)
+
 func NewWriter(w io.Writer) *Writer {
+
+	bw := bufio.NewWriter(w)
 	return &Writer{
 		Delimiter:       ',',
 		Quote:           '"',
 		AllQuotes:       false,
+		QuotePolicy:     QuoteMinimal,
 		RecordSeparator: "\r\n",
-		w:               bufio.NewWriter(w),
+		Comment:         '#',
+		out:             bw,
+		w:               bw,
 	}
 }
 
 func (w *Writer) Write(record []string) error {
 
+	if err := w.setup(); err != nil {
+		return err
+	}
+
+	w.numRecord++
+	if w.numRecord == 1 && w.HeaderQuotePolicy != nil {
+		w.header = slices.Clone(record)
+	}
+
+	if w.QuoteNone && w.Escape == 0 {
+		// Check all the fields before writing, so as not to write a part of the record.
+		for n, field := range record {
+			if w.containsFormat(field) || w.isCommentField(field, n) {
+				return &WriteError{Record: w.numRecord, Column: n + 1, Err: ErrQuoteRequired}
+			}
+		}
+	}
+
+	if w.Encoding != nil && w.Replacement == 0 {
+		// Check all the fields before writing, as well as QuoteNone.
+		for n, field := range record {
+			if err := w.checkEncoding(field); err != nil {
+				return &WriteError{Record: w.numRecord, Column: n + 1, Err: err}
+			}
+		}
+	}
+
 	for n, field := range record {
 		if n > 0 {
-			if _, err := w.w.WriteRune(w.Delimiter); err != nil {
+			if _, err := w.w.WriteString(w.delimiter()); err != nil {
 				return err
 			}
 		}
 
-		if w.fieldNeedsQuotes(field) {
+		if w.fieldNeedsQuotes(field, n) {
 			// Quoted field
-			if _, err := w.w.WriteRune(w.Quote); err != nil {
+			openQuote, closeQuote := w.quotes()
+			if _, err := w.w.WriteRune(openQuote); err != nil {
 				return err
 			}
 
-			if strings.ContainsRune(field, w.Quote) {
-				escaped := strings.ReplaceAll(field, string(w.Quote), string([]rune{w.Quote, w.Quote}))
+			if w.Escape != 0 {
+				if _, err := w.w.WriteString(w.escapeField(field, true)); err != nil {
+					return err
+				}
+			} else if strings.ContainsRune(field, closeQuote) {
+				escaped := strings.ReplaceAll(field, string(closeQuote), string([]rune{closeQuote, closeQuote}))
 				if _, err := w.w.WriteString(escaped); err != nil {
 					return err
 				}
@@ -61,12 +218,18 @@ func (w *Writer) Write(record []string) error {
 				}
 			}
 
-			if _, err := w.w.WriteRune(w.Quote); err != nil {
+			if _, err := w.w.WriteRune(closeQuote); err != nil {
 				return err
 			}
 
 		} else {
 			// Non quoted field
+			if w.Escape != 0 {
+				field = w.escapeField(field, false)
+				if w.isCommentField(field, n) {
+					field = string(w.Escape) + field
+				}
+			}
 			if _, err := w.w.WriteString(field); err != nil {
 				return err
 			}
@@ -77,8 +240,57 @@ func (w *Writer) Write(record []string) error {
 	return err
 }
 
+// WriteComment writes the comment lines.
+// Each line in the comment is written with the Comment character at the beginning.
+func (w *Writer) WriteComment(comment string) error {
+
+	if err := w.setup(); err != nil {
+		return err
+	}
+
+	if w.Encoding != nil {
+		// Check the comment before writing, as well as the fields in Write.
+		// The Comment character is not replaced even with Replacement, like the format characters.
+		text := string(w.Comment)
+		if w.Replacement == 0 {
+			text += comment
+		}
+		if err := w.checkEncoding(text); err != nil {
+			return &WriteError{Record: w.numRecord, Err: err}
+		}
+	}
+
+	if w.RecordSeparator != "" {
+		comment = strings.ReplaceAll(comment, w.RecordSeparator, "\n")
+	}
+	comment = newlineReplacer.Replace(comment)
+
+	for _, line := range strings.Split(comment, "\n") {
+		if _, err := w.w.WriteRune(w.Comment); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteString(line); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteString(w.RecordSeparator); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var newlineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
 func (w *Writer) Flush() error {
-	return w.w.Flush()
+
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if w.out != w.w {
+		return w.out.Flush()
+	}
+	return nil
 }
 
 func (w *Writer) WriteAll(records [][]string) error {
@@ -87,15 +299,339 @@ func (w *Writer) WriteAll(records [][]string) error {
 			return err
 		}
 	}
-	return w.w.Flush()
+	return w.Flush()
+}
+
+// setup validates the format characters, applies the Encoding and writes the BOM before the first output.
+func (w *Writer) setup() error {
+
+	if w.started {
+		return nil
+	}
+
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	w.started = true
+
+	if w.Encoding != nil {
+		w.w = bufio.NewWriter(&encodeWriter{w: w.out, encoding: w.Encoding, replacement: w.Replacement})
+	}
+
+	if w.BOM {
+		if w.Encoding != nil {
+			if _, ok := w.Encoding.AppendRune(nil, '\uFEFF'); !ok {
+				// The Encoding has no BOM.
+				return nil
+			}
+		}
+		if _, err := w.w.WriteRune('\uFEFF'); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the format characters do not conflict with each other.
+// It returns an error wrapping ErrInvalidDialect, which lists all the conflicts.
+// It is also checked by the first Write or WriteComment.
+func (w *Writer) Validate() error {
+
+	d := Dialect{
+		Delimiter:       w.Delimiter,
+		DelimiterString: w.DelimiterString,
+		Quote:           w.Quote,
+		OpenQuote:       w.OpenQuote,
+		CloseQuote:      w.CloseQuote,
+		QuoteNone:       w.QuoteNone,
+		Escape:          w.Escape,
+		RecordSeparator: w.RecordSeparator,
+	}
+
+	// The Comment character is written only at the beginning of a comment line, so it is not checked.
+	conflicts := d.conflicts()
+	if w.RecordSeparator == "" {
+		conflicts = append(conflicts, "record separator is empty")
+	}
+
+	if w.Encoding != nil {
+		// The format characters must not be replaced by Replacement, so they must be representable in the encoding.
+		addFormat := func(name string, text string) {
+			if w.checkEncoding(text) != nil {
+				conflicts = append(conflicts, fmt.Sprintf("%s %q cannot be represented in the encoding", name, text))
+			}
+		}
+
+		addFormat("delimiter", w.delimiter())
+		if !w.QuoteNone {
+			openQuote, closeQuote := w.quotes()
+			if openQuote == closeQuote {
+				addFormat("quote", string(openQuote))
+			} else {
+				addFormat("open quote", string(openQuote))
+				addFormat("close quote", string(closeQuote))
+			}
+		}
+		if w.Escape != 0 {
+			addFormat("escape", string(w.Escape))
+		}
+		addFormat("record separator", w.RecordSeparator)
+		if w.Replacement != 0 {
+			addFormat("replacement", string(w.Replacement))
+		}
+	}
+
+	return invalidDialectError(conflicts)
+}
+
+// checkEncoding checks that all the characters in the field can be represented in the Encoding.
+func (w *Writer) checkEncoding(field string) error {
+
+	var b [utf8.UTFMax * 2]byte
+	for _, c := range field {
+		if _, ok := w.Encoding.AppendRune(b[:0], c); !ok {
+			return fmt.Errorf("%w: %q", ErrUnrepresentable, c)
+		}
+	}
+	return nil
+}
+
+// encodeWriter converts the UTF-8 text written into the Encoding, and writes it to w.
+type encodeWriter struct {
+	w           *bufio.Writer
+	encoding    Encoding
+	replacement rune
+
+	// pending is the incomplete character at the end of the previous write.
+	pending []byte
+	buf     []byte
+}
+
+func (e *encodeWriter) Write(p []byte) (int, error) {
+
+	src := p
+	if len(e.pending) != 0 {
+		src = append(e.pending, p...)
+	}
+
+	e.buf = e.buf[:0]
+	i := 0
+	for i < len(src) && utf8.FullRune(src[i:]) {
+		c, size := utf8.DecodeRune(src[i:])
+
+		b, ok := e.encoding.AppendRune(e.buf, c)
+		if !ok && e.replacement != 0 {
+			b, ok = e.encoding.AppendRune(e.buf, e.replacement)
+		}
+		if !ok {
+			return 0, fmt.Errorf("%w: %q", ErrUnrepresentable, c)
+		}
+
+		e.buf = b
+		i += size
+	}
+	e.pending = append(e.pending[:0], src[i:]...)
+
+	if _, err := e.w.Write(e.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// delimiter returns the field delimiter to be written.
+func (w *Writer) delimiter() string {
+
+	if w.DelimiterString != "" {
+		return w.DelimiterString
+	}
+	return string(w.Delimiter)
+}
+
+// quotes returns the quote characters at the beginning and the end of a field.
+func (w *Writer) quotes() (rune, rune) {
+
+	openQuote, closeQuote := w.Quote, w.Quote
+	if w.OpenQuote != 0 {
+		openQuote = w.OpenQuote
+	}
+	if w.CloseQuote != 0 {
+		closeQuote = w.CloseQuote
+	}
+	return openQuote, closeQuote
+}
+
+func (w *Writer) fieldNeedsQuotes(field string, column int) bool {
+
+	if w.QuoteNone {
+		return false
+	}
+
+	switch w.columnQuotePolicy(column) {
+	case QuoteAll:
+		return true
+	case QuoteNonNumeric:
+		if !isNumeric(field) {
+			return true
+		}
+	case QuoteNonEmpty:
+		if field != "" {
+			return true
+		}
+	}
+
+	if w.QuoteSpaces && (strings.HasPrefix(field, " ") || strings.HasSuffix(field, " ")) {
+		return true
+	}
+
+	if w.Escape != 0 {
+		// Escaped instead of quoted.
+		return false
+	}
+
+	openQuote, closeQuote := w.quotes()
+	return w.containsFormat(field) || w.isCommentField(field, column) ||
+		strings.ContainsRune(field, openQuote) || strings.ContainsRune(field, closeQuote)
+}
+
+// isCommentField reports whether the field would be read as the beginning of a comment line.
+func (w *Writer) isCommentField(field string, column int) bool {
+	return column == 0 && w.Comment != 0 && strings.HasPrefix(field, string(w.Comment))
 }
 
-func (w *Writer) fieldNeedsQuotes(field string) bool {
+// columnQuotePolicy returns the quote policy of the column.
+func (w *Writer) columnQuotePolicy(column int) QuotePolicy {
+
+	if policy, ok := w.ColumnQuotePolicy[column]; ok {
+		return policy
+	}
+
+	if column < len(w.header) {
+		if policy, ok := w.HeaderQuotePolicy[w.header[column]]; ok {
+			return policy
+		}
+	}
 
 	if w.AllQuotes {
+		return QuoteAll
+	}
+	return w.QuotePolicy
+}
+
+// isNumeric reports whether the field is a decimal number, such as "-12", "3.14" or "1e10".
+func isNumeric(field string) bool {
+
+	s := strings.TrimLeft(field, "+-")
+	if len(field)-len(s) > 1 {
+		return false
+	}
+
+	digits := 0
+	i := 0
+	for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exponent := i
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		}
+		if i == exponent {
+			return false
+		}
+	}
+
+	return i == len(s)
+}
+
+// containsFormat reports whether the field contains the delimiter, the record separator or a newline.
+func (w *Writer) containsFormat(field string) bool {
+	return w.containsSequence(field, w.delimiter()) ||
+		w.containsSequence(field, w.RecordSeparator) || strings.ContainsAny(field, "\r\n")
+}
+
+// containsSequence reports whether the sequence (the delimiter or the record separator) is in the field,
+// or may be formed with the characters that follow the field.
+func (w *Writer) containsSequence(field string, sequence string) bool {
+
+	if sequence == "" {
+		return false
+	}
+
+	if strings.Contains(field, sequence) {
 		return true
 	}
 
-	return strings.ContainsRune(field, w.Delimiter) || strings.ContainsRune(field, w.Quote) ||
-		strings.ContainsAny(field, w.RecordSeparator) || strings.ContainsAny(field, "\r\n")
+	// The end of the field is the beginning of the sequence,
+	// and the rest of it may be formed by the delimiter or the record separator that follows.
+	for i := 1; i < len(sequence); i++ {
+		if !strings.HasSuffix(field, sequence[:i]) {
+			continue
+		}
+
+		rest := sequence[i:]
+		for _, next := range []string{w.delimiter(), w.RecordSeparator} {
+			if strings.HasPrefix(rest, next) || strings.HasPrefix(next, rest) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// escapeField writes the Escape character before the characters that would be read as format characters.
+// In a quoted field, they are the closing quote and the Escape character.
+// Otherwise, they are the first characters of the delimiter, the quotes, the record separator and newlines.
+func (w *Writer) escapeField(field string, quoted bool) string {
+
+	openQuote, closeQuote := w.quotes()
+	special := []rune{w.Escape, closeQuote}
+	if !quoted {
+		special = append(special, openQuote, '\r', '\n')
+		for _, sequence := range []string{w.delimiter(), w.RecordSeparator} {
+			if sequence != "" {
+				first, _ := utf8.DecodeRuneInString(sequence)
+				special = append(special, first)
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range field {
+		if w.EscapeSequences {
+			if s, ok := escapeSequenceNames[c]; ok {
+				b.WriteRune(w.Escape)
+				b.WriteByte(s)
+				continue
+			}
+		}
+
+		if slices.Contains(special, c) {
+			b.WriteRune(w.Escape)
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// escapeSequenceNames is the characters following the Escape character in the escape sequences.
+var escapeSequenceNames = map[rune]byte{
+	'\n': 'n',
+	'\t': 't',
+	'\r': 'r',
+	0:    '0',
 }
@@ -3,6 +3,9 @@ package customcsv
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -69,6 +72,48 @@ func TestNewWriter_Delimiter(t *testing.T) {
 	}
 }
 
+func TestNewWriter_DelimiterString(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.DelimiterString = "||"
+
+	if err := cw.Write([]string{"1", "2", ","}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"a|b", "c||d", "e|", "|f"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	// "e|" is quoted, since it forms the delimiter with the following "||".
+	expect := "1||2||,\r\n" +
+		"a|b||\"c||d\"||\"e|\"|||f\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+
+	r := NewReader(strings.NewReader(result))
+	r.DelimiterString = "||"
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"1", "2", ","}, {"a|b", "c||d", "e|", "|f"}}) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
 func TestNewWriter_Quote(t *testing.T) {
 
 	var b bytes.Buffer
@@ -101,6 +146,44 @@ func TestNewWriter_Quote(t *testing.T) {
 	}
 }
 
+func TestNewWriter_OpenCloseQuote(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.OpenQuote = '「'
+	cw.CloseQuote = '」'
+
+	if err := cw.Write([]string{"a", "b,c", "「d", "e」f", "\""}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "a,「b,c」,「「d」,「e」」f」,\"\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+
+	r := NewReader(strings.NewReader(result))
+	r.OpenQuote = '「'
+	r.CloseQuote = '」'
+
+	record, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(record, []string{"a", "b,c", "「d", "e」f", "\""}) {
+		t.Fatal("failed test\n", record)
+	}
+}
+
 func TestNewWriter_AllQuotes(t *testing.T) {
 
 	var b bytes.Buffer
@@ -133,20 +216,57 @@ func TestNewWriter_AllQuotes(t *testing.T) {
 	}
 }
 
-func TestNewWriter_RecordSeparator(t *testing.T) {
+func TestNewWriter_Escape(t *testing.T) {
+
+	record := []string{"a,b", "c\"d", "e\\f", "g\nh", "i\r\nj", "k\tl", ""}
 
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
 	cw := NewWriter(w)
-	cw.RecordSeparator = "|"
+	cw.Escape = '\\'
 
-	if err := cw.Write([]string{"1", "2"}); err != nil {
+	if err := cw.Write(record); err != nil {
 		t.Fatal("failed test\n", err)
 	}
-	if err := cw.Write([]string{"|", ","}); err != nil {
+
+	if err := cw.Flush(); err != nil {
 		t.Fatal("failed test\n", err)
 	}
-	if err := cw.Write([]string{"\r", "\n"}); err != nil {
+
+	result := b.String()
+
+	expect := `a\,b,c\"d,e\\f,g\` + "\n" + `h,i\` + "\r" + `\` + "\n" + "j,k\tl,\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+
+	r := NewReader(strings.NewReader(result))
+	r.Escape = '\\'
+
+	read, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(read, record) {
+		t.Fatal("failed test\n", read)
+	}
+}
+
+func TestNewWriter_Escape_EscapeSequences(t *testing.T) {
+
+	record := []string{"a\tb", "c\nd", "e\r\x00", "f\\n"}
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.Delimiter = '\t'
+	cw.RecordSeparator = "\n"
+	cw.Escape = '\\'
+	cw.EscapeSequences = true
+
+	if err := cw.Write(record); err != nil {
 		t.Fatal("failed test\n", err)
 	}
 
@@ -156,39 +276,924 @@ func TestNewWriter_RecordSeparator(t *testing.T) {
 
 	result := b.String()
 
-	expect := "1,2|" +
-		"\"|\",\",\"|" +
-		"\"\r\",\"\n\"|"
+	expect := `a\tb` + "\t" + `c\nd` + "\t" + `e\r\0` + "\t" + `f\\n` + "\n"
 
 	if result != expect {
 		t.Fatal("failed test\n", result)
 	}
+
+	r := NewReader(strings.NewReader(result))
+	r.Delimiter = '\t'
+	r.Escape = '\\'
+	r.EscapeSequences = true
+
+	read, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(read, record) {
+		t.Fatal("failed test\n", read)
+	}
 }
 
-func TestNewWriter_WriteAll(t *testing.T) {
+func TestNewWriter_Escape_AllQuotes(t *testing.T) {
+
+	record := []string{"a,b", "c\"d", "e\\f", "g\nh"}
 
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
 	cw := NewWriter(w)
+	cw.Escape = '\\'
+	cw.AllQuotes = true
 
-	err := cw.WriteAll(
-		[][]string{
-			{"1", "2", "3", "4"},
-			{"", ",", "\"", "\"x\""},
-			{"\r", "\n", "\r\n", "a\r\nb\nc"},
-		})
+	if err := cw.Write(record); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := `"a,b","c\"d","e\\f","g` + "\n" + `h"` + "\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+
+	r := NewReader(strings.NewReader(result))
+	r.Escape = '\\'
+
+	read, err := r.Read()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(read, record) {
+		t.Fatal("failed test\n", read)
+	}
+}
+
+func TestNewWriter_Escape_RecordSeparator(t *testing.T) {
+
+	record := []string{"a||b", "c|"}
 
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.RecordSeparator = "||"
+	cw.Escape = '\\'
+
+	if err := cw.Write(record); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	// Each character that begins the record separator is escaped.
+	expect := `a\|\|b,c\|||`
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+
+	r := NewReader(strings.NewReader(result))
+	r.SpecialRecordSeparator = "||"
+	r.Escape = '\\'
+
+	read, err := r.Read()
 	if err != nil {
 		t.Fatal("failed test\n", err)
 	}
 
+	if !reflect.DeepEqual(read, record) {
+		t.Fatal("failed test\n", read)
+	}
+}
+
+func TestNewWriter_QuoteNone(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.QuoteNone = true
+	cw.AllQuotes = true
+
+	if err := cw.Write([]string{"a\"b", "\"", ""}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
 	result := b.String()
 
-	expect := "1,2,3,4\r\n" +
-		",\",\",\"\"\"\",\"\"\"x\"\"\"\r\n" +
-		"\"\r\",\"\n\",\"\r\n\",\"a\r\nb\nc\"\r\n"
+	expect := "a\"b,\",\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_QuoteNone_Error(t *testing.T) {
+
+	tests := []struct {
+		record []string
+		column int
+	}{
+		{record: []string{"a", "b,c"}, column: 2},
+		{record: []string{"a\r\nb"}, column: 1},
+		{record: []string{"a", "b", "c|"}, column: 3},
+		{record: []string{"a|", "b"}, column: 1},
+		{record: []string{"#a", "b"}, column: 1},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		w := bufio.NewWriter(&b)
+		cw := NewWriter(w)
+		cw.QuoteNone = true
+		cw.RecordSeparator = "||"
+
+		if err := cw.Write([]string{"x"}); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		err := cw.Write(test.record)
+		if !errors.Is(err, ErrQuoteRequired) {
+			t.Fatal("failed test\n", test.record, err)
+		}
+
+		we := err.(*WriteError)
+		if we.Record != 2 || we.Column != test.column {
+			t.Fatal("failed test\n", test.record, we)
+		}
+
+		if err := cw.Flush(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		// Nothing of the record is written.
+		if b.String() != "x||" {
+			t.Fatal("failed test\n", b.String())
+		}
+	}
+}
+
+func TestNewWriter_QuoteNone_Escape(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.QuoteNone = true
+	cw.Escape = '\\'
+
+	if err := cw.Write([]string{"a,b", "c\nd"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := `a\,b,c\` + "\n" + "d\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_QuotePolicy(t *testing.T) {
+
+	record := []string{"a", "", "12", "-3.5e+2", " b", "c,d"}
+
+	tests := []struct {
+		policy QuotePolicy
+		expect string
+	}{
+		{policy: QuoteMinimal, expect: "a,,12,-3.5e+2, b,\"c,d\"\r\n"},
+		{policy: QuoteAll, expect: "\"a\",\"\",\"12\",\"-3.5e+2\",\" b\",\"c,d\"\r\n"},
+		{policy: QuoteNonNumeric, expect: "\"a\",\"\",12,-3.5e+2,\" b\",\"c,d\"\r\n"},
+		{policy: QuoteNonEmpty, expect: "\"a\",,\"12\",\"-3.5e+2\",\" b\",\"c,d\"\r\n"},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		w := bufio.NewWriter(&b)
+		cw := NewWriter(w)
+		cw.QuotePolicy = test.policy
+
+		if err := cw.Write(record); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if err := cw.Flush(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		result := b.String()
+		if result != test.expect {
+			t.Fatal("failed test\n", test.policy, result)
+		}
+	}
+}
+
+func TestNewWriter_ColumnQuotePolicy(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.QuotePolicy = QuoteNonNumeric
+	cw.ColumnQuotePolicy = map[int]QuotePolicy{
+		0: QuoteAll,
+		2: QuoteMinimal,
+	}
+	cw.HeaderQuotePolicy = map[string]QuotePolicy{
+		"name": QuoteNonEmpty,
+		"code": QuoteAll,
+		"memo": QuoteMinimal,
+	}
+
+	if err := cw.Write([]string{"id", "name", "code", "memo"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"1", "", "2", "x"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"3", "y", "z", "", "5"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	// The policy by index takes precedence over the policy by header name.
+	expect := "\"id\",\"name\",code,memo\r\n" +
+		"\"1\",,2,x\r\n" +
+		"\"3\",\"y\",z,,5\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_QuoteSpaces(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.QuoteSpaces = true
+
+	if err := cw.Write([]string{" a", "b ", "c d", " "}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "\" a\",\"b \",c d,\" \"\r\n"
 
 	if result != expect {
 		t.Fatal("failed test\n", result)
 	}
 }
+
+func TestIsNumeric(t *testing.T) {
+
+	tests := []struct {
+		field    string
+		expected bool
+	}{
+		{"0", true},
+		{"123", true},
+		{"-1", true},
+		{"+1", true},
+		{"1.5", true},
+		{".5", true},
+		{"5.", true},
+		{"1e10", true},
+		{"1.2E-3", true},
+		{"", false},
+		{"-", false},
+		{".", false},
+		{"+-1", false},
+		{"1e", false},
+		{"1e+", false},
+		{"e1", false},
+		{"1,000", false},
+		{" 1", false},
+		{"0x10", false},
+		{"NaN", false},
+		{"Inf", false},
+	}
+
+	for _, test := range tests {
+		if isNumeric(test.field) != test.expected {
+			t.Fatal("failed test\n", test.field)
+		}
+	}
+}
+
+func TestNewWriter_RecordSeparator(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.RecordSeparator = "|"
+
+	if err := cw.Write([]string{"1", "2"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"|", ","}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"\r", "\n"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "1,2|" +
+		"\"|\",\",\"|" +
+		"\"\r\",\"\n\"|"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_RecordSeparator_MultiChar(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.RecordSeparator = "[RS]"
+
+	if err := cw.Write([]string{"[", "RS", "]", "R[S"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"a[RS]b", "a[R", "[RS"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	// Only the fields that contain the whole separator are quoted.
+	expect := "[,RS,],R[S[RS]" +
+		"\"a[RS]b\",a[R,[RS[RS]"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_RecordSeparator_Overlap(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.RecordSeparator = "||"
+
+	if err := cw.Write([]string{"|", "a|b", "c|"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	// A field ending with "|" is quoted, since it forms the separator with the following "||".
+	expect := "\"|\",a|b,\"c|\"||"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+
+	r := NewReader(strings.NewReader(result))
+	r.SpecialRecordSeparator = "||"
+
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if !reflect.DeepEqual(records, [][]string{{"|", "a|b", "c|"}}) {
+		t.Fatal("failed test\n", records)
+	}
+}
+
+func TestNewWriter_RecordSeparator_NonAscii(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.RecordSeparator = "␞"
+
+	if err := cw.Write([]string{"␟", "a␞b", "あ"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "␟,\"a␞b\",あ␞"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_UnitRecordSeparator(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.Delimiter = '\x1f'
+	cw.RecordSeparator = "\x1e"
+
+	if err := cw.Write([]string{"a,b", "c\nd", "e\x1ff"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"g\x1eh", ""}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "a,b\x1f\"c\nd\"\x1f\"e\x1ff\"\x1e" +
+		"\"g\x1eh\"\x1f\x1e"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_WriteAll(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+
+	err := cw.WriteAll(
+		[][]string{
+			{"1", "2", "3", "4"},
+			{"", ",", "\"", "\"x\""},
+			{"\r", "\n", "\r\n", "a\r\nb\nc"},
+		})
+
+	if err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "1,2,3,4\r\n" +
+		",\",\",\"\"\"\",\"\"\"x\"\"\"\r\n" +
+		"\"\r\",\"\n\",\"\r\n\",\"a\r\nb\nc\"\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_WriteComment(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+
+	if err := cw.WriteComment("comment"); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"1", "2"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.WriteComment("a\r\nb\nc"); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "#comment\r\n" +
+		"1,2\r\n" +
+		"#a\r\n#b\r\n#c\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_WriteComment_Custom(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.Comment = ';'
+	cw.RecordSeparator = "|"
+
+	if err := cw.WriteComment("a|b"); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+	if err := cw.Write([]string{"1", "2"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := ";a|;b|1,2|"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_CommentField_RoundTrip(t *testing.T) {
+
+	records := [][]string{
+		{"#a", "b"},
+		{"c", "#d"},
+		{"#", ""},
+	}
+
+	tests := []struct {
+		setup  func(w *Writer)
+		expect string
+	}{
+		{
+			setup:  func(w *Writer) {},
+			expect: "#x\r\n\"#a\",b\r\nc,#d\r\n\"#\",\r\n",
+		},
+		{
+			setup: func(w *Writer) {
+				w.QuoteNone = true
+				w.Escape = '\\'
+			},
+			expect: "#x\r\n\\#a,b\r\nc,#d\r\n\\#,\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		cw := NewWriter(&b)
+		test.setup(cw)
+
+		if err := cw.WriteComment("x"); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		if err := cw.WriteAll(records); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if b.String() != test.expect {
+			t.Fatal("failed test\n", b.String())
+		}
+
+		// The records are not read as comment lines.
+		r := NewReader(strings.NewReader(b.String()))
+		r.Comment = '#'
+		r.QuoteNone = cw.QuoteNone
+		r.Escape = cw.Escape
+
+		result, err := r.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(result, records) {
+			t.Fatal("failed test\n", result)
+		}
+	}
+}
+
+func TestNewWriter_BOM(t *testing.T) {
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	cw := NewWriter(w)
+	cw.BOM = true
+
+	if err := cw.WriteAll([][]string{{"a", "b"}, {"c", "d"}}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	result := b.String()
+
+	expect := "\uFEFFa,b\r\nc,d\r\n"
+
+	if result != expect {
+		t.Fatal("failed test\n", result)
+	}
+}
+
+func TestNewWriter_Encoding(t *testing.T) {
+
+	tests := []struct {
+		encoding Encoding
+		bom      bool
+		expect   string
+	}{
+		{Windows31J, false, "\x82\xA0,\"\xB6,\x87\x40\"\r\n"},
+		// Windows-31J has no BOM.
+		{Windows31J, true, "\x82\xA0,\"\xB6,\x87\x40\"\r\n"},
+		{EUCJP, false, "\xA4\xA2,\"\x8E\xB6,\xAD\xA1\"\r\n"},
+		{UTF16LE, true, "\xFF\xFE\x42\x30,\x00\"\x00\x76\xFF,\x00\x60\x24\"\x00\r\x00\n\x00"},
+		{UTF16BE, false, "\x30\x42\x00,\x00\"\xFF\x76\x00,\x24\x60\x00\"\x00\r\x00\n"},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		w := bufio.NewWriter(&b)
+		cw := NewWriter(w)
+		cw.Encoding = test.encoding
+		cw.BOM = test.bom
+
+		if err := cw.WriteAll([][]string{{"あ", "ｶ,①"}}); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		result := b.String()
+
+		if result != test.expect {
+			t.Fatalf("failed test\n% X", result)
+		}
+	}
+}
+
+func TestNewWriter_Encoding_Unrepresentable(t *testing.T) {
+
+	var b bytes.Buffer
+	cw := NewWriter(&b)
+	cw.Encoding = Windows31J
+
+	if err := cw.Write([]string{"a", "b"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	err := cw.Write([]string{"c", "d", "e😀"})
+	if !errors.Is(err, ErrUnrepresentable) {
+		t.Fatal("failed test\n", err)
+	}
+
+	we := err.(*WriteError)
+	if we.Record != 2 || we.Column != 3 {
+		t.Fatal("failed test\n", we)
+	}
+
+	if err.Error() != "write error on record 2, column 3: character cannot be represented in the encoding: '😀'" {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// Nothing of the record is written.
+	if b.String() != "a,b\r\n" {
+		t.Fatal("failed test\n", b.String())
+	}
+}
+
+func TestNewWriter_Encoding_Replacement(t *testing.T) {
+
+	var b bytes.Buffer
+	cw := NewWriter(&b)
+	cw.Encoding = EUCJP
+	cw.Replacement = '?'
+
+	if err := cw.WriteAll([][]string{{"a😀", "い"}}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if b.String() != "a?,\xA4\xA4\r\n" {
+		t.Fatalf("failed test\n% X", b.String())
+	}
+}
+
+func TestNewWriter_Encoding_WriteComment(t *testing.T) {
+
+	var b bytes.Buffer
+	cw := NewWriter(&b)
+	cw.Encoding = Windows31J
+
+	if err := cw.WriteComment("コメント"); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Write([]string{"a"}); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	err := cw.WriteComment("b😀")
+	if !errors.Is(err, ErrUnrepresentable) {
+		t.Fatal("failed test\n", err)
+	}
+
+	we := err.(*WriteError)
+	if we.Record != 1 || we.Column != 0 {
+		t.Fatal("failed test\n", we)
+	}
+
+	if err.Error() != "write error on comment after record 1: character cannot be represented in the encoding: '😀'" {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The Comment character is also checked.
+	cw.Comment = '😀'
+	if err := cw.WriteComment("c"); !errors.Is(err, ErrUnrepresentable) {
+		t.Fatal("failed test\n", err)
+	}
+
+	// Nothing of the comment is written, and the writer can be used continuously.
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if b.String() != "#\x83R\x83\x81\x83\x93\x83g\r\na\r\n" {
+		t.Fatalf("failed test\n% X", b.String())
+	}
+}
+
+func TestNewWriter_Encoding_WriteComment_Replacement(t *testing.T) {
+
+	var b bytes.Buffer
+	cw := NewWriter(&b)
+	cw.Encoding = EUCJP
+	cw.Replacement = '?'
+
+	// The text of the comment is replaced.
+	if err := cw.WriteComment("a😀"); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	// The Comment character is not replaced.
+	cw.Comment = '😀'
+	if err := cw.WriteComment("b"); !errors.Is(err, ErrUnrepresentable) {
+		t.Fatal("failed test\n", err)
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+
+	if b.String() != "#a?\r\n" {
+		t.Fatalf("failed test\n% X", b.String())
+	}
+}
+
+func TestNewWriter_Encoding_RoundTrip(t *testing.T) {
+
+	records := [][]string{
+		{"名前", "値"},
+		{"\"引用\"", "改\r\n行"},
+	}
+
+	for _, encoding := range []Encoding{Windows31J, EUCJP, UTF16LE, UTF16BE} {
+		var b bytes.Buffer
+		cw := NewWriter(&b)
+		cw.Encoding = encoding
+		cw.BOM = true
+
+		if err := cw.WriteAll(records); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		r := NewReader(&b)
+		if encoding != UTF16LE && encoding != UTF16BE {
+			// UTF-16 is detected by the BOM.
+			r.Encoding = encoding
+		}
+
+		result, err := r.ReadAll()
+		if err != nil {
+			t.Fatal("failed test\n", err)
+		}
+
+		if !reflect.DeepEqual(result, records) {
+			t.Fatal("failed test\n", result)
+		}
+	}
+}
+
+func TestWriter_Validate(t *testing.T) {
+
+	tests := []struct {
+		setup   func(w *Writer)
+		message string
+	}{
+		{
+			setup:   func(w *Writer) { w.Delimiter = '"' },
+			message: `invalid dialect: delimiter "\"" conflicts with quote "\""`,
+		},
+		{
+			setup:   func(w *Writer) { w.RecordSeparator = "" },
+			message: `invalid dialect: record separator is empty`,
+		},
+		{
+			setup: func(w *Writer) {
+				w.RecordSeparator = "|"
+				w.Escape = '|'
+			},
+			message: `invalid dialect: escape "|" conflicts with record separator "|"`,
+		},
+		{
+			setup: func(w *Writer) {
+				w.Encoding = Windows31J
+				w.OpenQuote = '«'
+				w.CloseQuote = '»'
+			},
+			message: `invalid dialect: open quote "«" cannot be represented in the encoding; close quote "»" cannot be represented in the encoding`,
+		},
+		{
+			setup: func(w *Writer) {
+				// The delimiter is not replaced by Replacement.
+				w.Encoding = Windows31J
+				w.Replacement = '?'
+				w.Delimiter = '€'
+			},
+			message: `invalid dialect: delimiter "€" cannot be represented in the encoding`,
+		},
+		{
+			setup: func(w *Writer) {
+				w.Encoding = Windows31J
+				w.Replacement = '😀'
+			},
+			message: `invalid dialect: replacement "😀" cannot be represented in the encoding`,
+		},
+	}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		w := NewWriter(&b)
+		test.setup(w)
+
+		err := w.Validate()
+		if !errors.Is(err, ErrInvalidDialect) || err.Error() != test.message {
+			t.Fatal("failed test\n", err)
+		}
+
+		// It is also checked by Write, and nothing is written.
+		err = w.Write([]string{"a", "b"})
+		if !errors.Is(err, ErrInvalidDialect) || err.Error() != test.message {
+			t.Fatal("failed test\n", err)
+		}
+
+		if err := w.Flush(); err != nil {
+			t.Fatal("failed test\n", err)
+		}
+		if b.Len() != 0 {
+			t.Fatal("failed test\n", b.String())
+		}
+	}
+}
+
+func TestWriter_Validate_Comment(t *testing.T) {
+
+	// The Comment character may be the same as the delimiter.
+	var b bytes.Buffer
+	w := NewWriter(&b)
+	w.Delimiter = '#'
+
+	if err := w.Validate(); err != nil {
+		t.Fatal("failed test\n", err)
+	}
+}